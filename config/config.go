@@ -5,6 +5,8 @@ package config
 
 import (
 	// Standard lib
+	"fmt"
+	"runtime"
 	"time"
 
 	// Third-party
@@ -23,6 +25,9 @@ const (
 	// Environment variable where the path to an outside
 	// configuration file is located
 	CONFIG_LOCATION = ENV_PREFIX + "CONFIG"
+
+	// Value secrets are replaced with when configuration is logged
+	REDACTED = "[redacted]"
 )
 
 var (
@@ -32,12 +37,105 @@ var (
 type (
 	// Component-specific configuration
 
+	// Struct containing configuration settings for administrative routes
+	Admin struct {
+		// Token required to access administrative routes
+		// NOTE: Administrative routes are disabled when empty
+		Token string `json:"token" env:"ADMIN_TOKEN"`
+	}
+
+	// Struct containing configuration settings for API key authentication
+	Auth struct {
+		// Map of API keys to the policies applied to requests made with them
+		// NOTE: Authentication is disabled when no keys are configured
+		Keys map[string]*KeyPolicy `json:"keys"`
+	}
+
+	// Struct containing the restrictions applied to requests made with a single API key
+	KeyPolicy struct {
+		// Max height (in pixels) of output images (0 for no limit)
+		MaxHeight int `json:"max-height"`
+		// Max width (in pixels) of output images (0 for no limit)
+		MaxWidth int `json:"max-width"`
+		// Names of the operations allowed to be run (empty allows all operations)
+		Operations []string `json:"operations"`
+		// Rate limiting overrides for the key
+		RateLimit struct {
+			// Max number of requests the key can make at once before being limited
+			Burst int `json:"burst"`
+			// Number of requests per second the key is allowed (0 uses the server's rate limit)
+			Rate int `json:"rate"`
+		} `json:"rate-limit"`
+		// Source aliases (the hosts images are requested from) allowed
+		// to be used (empty allows all sources)
+		Sources []string `json:"sources"`
+	}
+
+	// Struct containing configuration settings for caching
+	Cache struct {
+		// Settings for caching downloaded source images
+		Sources struct {
+			// Whether source images should be cached or not
+			Enabled bool `json:"enabled" env:"CACHE_SOURCES_ENABLED"`
+			// Max number of source images to keep in the cache
+			MaxEntries int `json:"max-entries" env:"CACHE_SOURCES_MAX_ENTRIES"`
+			// Max total size (in megabytes) of source images to keep in the cache
+			MaxSize int `json:"max-size" env:"CACHE_SOURCES_MAX_SIZE"`
+			// Time (in seconds) a cached source image is considered fresh before
+			// it's revalidated against the origin
+			TTL int `json:"ttl" env:"CACHE_SOURCES_TTL"`
+		} `json:"sources"`
+	}
+
 	// Struct containing configuration settings for image processing
 	Images struct {
+		// Settings for processing animated PNG images
+		Apng struct {
+			// How animated PNGs are handled (one of: "static" to process only their default image,
+			// "passthrough" to serve them untouched, "gif" to process all frames and output them
+			// as an animated GIF, or "webp" to output them as an animated WebP)
+			Mode string `json:"mode" env:"IMAGE_APNG_MODE"`
+		} `json:"apng"`
+		// Limits on the number of images processed at once
+		Concurrency struct {
+			// Max number of GIF images processed at once (0 for no limit)
+			Gif int `json:"gif" env:"IMAGE_CONCURRENCY_GIF"`
+			// Max number of requests allowed to wait for a processing slot, per image type
+			Queue int `json:"queue" env:"IMAGE_CONCURRENCY_QUEUE"`
+			// Time (in seconds) clients are asked to wait before retrying when the queue is full
+			RetryAfter int `json:"retry-after" env:"IMAGE_CONCURRENCY_RETRY_AFTER"`
+			// Max number of static images processed at once (0 for no limit)
+			Static int `json:"static" env:"IMAGE_CONCURRENCY_STATIC"`
+		} `json:"concurrency"`
 		// Default quality all images should be output at without request overrides
 		DefaultQuality int `json:"default-quality" env:"IMAGE_DEFAULT_QUALITY"`
+		// Placeholder images served in place of source images that can't be downloaded
+		Fallback struct {
+			// Path to the placeholder image used for all sources (empty disables fallbacks)
+			Default string `json:"default" env:"IMAGE_FALLBACK_DEFAULT"`
+			// Map of source aliases (the hosts images are requested from) to the paths
+			// of placeholder images used for them, overriding the default
+			Sources map[string]string `json:"sources"`
+			// Status code to respond with when serving a placeholder image
+			// (0 preserves the status code of the original error)
+			Status int `json:"status" env:"IMAGE_FALLBACK_STATUS"`
+		} `json:"fallback"`
+		// Settings for processing GIF images
+		Gif struct {
+			// Engine used to process GIFs (one of: "gifsicle", "native", or "auto"
+			// to use gifsicle when it's installed, and the native engine otherwise)
+			Engine string `json:"engine" env:"IMAGE_GIF_ENGINE"`
+			// Max number of gifsicle processes run at once (0 for no limit)
+			MaxProcesses int `json:"max-processes" env:"IMAGE_GIF_MAX_PROCESSES"`
+			// Time (in seconds) a gifsicle process is allowed to run before it's killed
+			// (0 for no limit)
+			Timeout int `json:"timeout" env:"IMAGE_GIF_TIMEOUT"`
+		} `json:"gif"`
 		// Max-width of the image before switching interpolators
 		InterpolatorThreshold int64 `json:"interpolator-threshold" env:"IMAGE_INTERPOLATOR_THRESHOLD"`
+		// Whether a Server-Timing header with a breakdown of processing times should be
+		// added to all image responses, rather than only to debug requests
+		ServerTiming bool `json:"server-timing" env:"IMAGE_SERVER_TIMING"`
 	}
 
 	// Struct containing configuration settings for application logging
@@ -52,15 +150,39 @@ type (
 	Server struct {
 		// Port the server should listen on
 		Port int `json:"port" env:"SERVER_PORT"`
+		// Per-client rate limiting
+		RateLimit struct {
+			// Max number of requests a client can make at once before being limited
+			Burst int `json:"burst" env:"SERVER_RATE_LIMIT_BURST"`
+			// Number of requests per second a client is allowed (0 disables rate limiting)
+			Rate int `json:"rate" env:"SERVER_RATE_LIMIT_RATE"`
+			// Comma-separated list of proxy IPs or CIDR ranges whose X-Forwarded-For headers are trusted
+			TrustedProxies string `json:"trusted-proxies" env:"SERVER_RATE_LIMIT_TRUSTED_PROXIES"`
+		} `json:"rate-limit"`
 		// Various timeouts for the server
 		Timeouts struct {
+			// Time (in seconds) the server keeps accepting connections with readiness checks
+			// failing when shutting down, so orchestrators can stop routing requests to it
+			DrainDelay int `json:"drain-delay" env:"SERVER_DRAIN_DELAY"`
 			// Timeout (in seconds) allowed for server read operations
 			Read int `json:"read" env:"SERVER_READ_TIMEOUT"`
+			// Timeout (in seconds) allowed for in-flight requests to finish when shutting down
+			Shutdown int `json:"shutdown" env:"SERVER_SHUTDOWN_TIMEOUT"`
 			// Timeout (in seconds) allowed for server write operations
 			Write int `json:"write" env:"SERVER_WRITE_TIMEOUT"`
 		} `json:"timeouts"`
 	}
 
+	// Struct containing configuration settings for tracing
+	Tracing struct {
+		// Endpoint of the OTLP/HTTP collector spans are sent to when using the "otlp" exporter
+		Endpoint string `json:"endpoint" env:"TRACING_ENDPOINT"`
+		// Exporter spans are exported with (one of: "otlp", "file", or empty to disable tracing)
+		Exporter string `json:"exporter" env:"TRACING_EXPORTER"`
+		// Path of the file spans are written to when using the "file" exporter
+		File string `json:"file" env:"TRACING_FILE"`
+	}
+
 	// Config is a struct containing all configuration settings for the application.
 	// NOTE: Only a single instance of this struct should be used throughout the application
 	// so as to reference the same configuration state.
@@ -82,6 +204,15 @@ type (
 
 		/* Component-specific configuration */
 
+		// Settings for administrative routes
+		Admin Admin `json:"admin"`
+
+		// Settings for API key authentication
+		Auth Auth `json:"auth"`
+
+		// Settings for caching
+		Cache Cache `json:"cache"`
+
 		// Settings for image processing
 		Images Images `json:"images"`
 
@@ -90,6 +221,9 @@ type (
 
 		// Settings for the server
 		Server Server `json:"server"`
+
+		// Settings for tracing
+		Tracing Tracing `json:"tracing"`
 	}
 )
 
@@ -119,9 +253,24 @@ func (c *Config) setDefaults() {
 	c.StartTime = time.Now()
 	c.Version = "v1"
 
+	// Cache defaults
+	c.Cache.Sources.Enabled = true
+	c.Cache.Sources.MaxEntries = 500
+	c.Cache.Sources.MaxSize = 256 // In megabytes
+	c.Cache.Sources.TTL = 300     // In seconds
+
 	// Image defaults
+	c.Images.Apng.Mode = "static"
+	c.Images.Concurrency.Gif = runtime.NumCPU()
+	c.Images.Concurrency.Queue = 100
+	c.Images.Concurrency.RetryAfter = 1 // In seconds
+	c.Images.Concurrency.Static = runtime.NumCPU()
 	c.Images.DefaultQuality = 75
+	c.Images.Gif.Engine = "auto"
+	c.Images.Gif.MaxProcesses = runtime.NumCPU()
+	c.Images.Gif.Timeout = 30 // In seconds
 	c.Images.InterpolatorThreshold = 300
+	c.Images.ServerTiming = false
 
 	// Logger defaults
 	c.Log.Formatter = "text"
@@ -129,8 +278,17 @@ func (c *Config) setDefaults() {
 
 	// Server defaults
 	c.Server.Port = 6060
-	c.Server.Timeouts.Read = 30  // In seconds
-	c.Server.Timeouts.Write = 30 // In seconds
+	c.Server.RateLimit.Burst = 20
+	c.Server.RateLimit.Rate = 0      // Disabled
+	c.Server.Timeouts.DrainDelay = 5 // In seconds
+	c.Server.Timeouts.Read = 30      // In seconds
+	c.Server.Timeouts.Shutdown = 30  // In seconds
+	c.Server.Timeouts.Write = 30     // In seconds
+
+	// Tracing defaults
+	c.Tracing.Endpoint = "http://localhost:4318"
+	c.Tracing.Exporter = "" // Disabled
+	c.Tracing.File = "traces.json"
 }
 
 // setLoggerSettings sets the application logger's various properties
@@ -179,6 +337,31 @@ func (c *Config) IsTesting() bool {
 	return c.Environment == ENV_TESTING
 }
 
+// Redacted returns a copy of the configuration with secrets, such as tokens and API keys,
+// replaced so that it can be safely logged
+func (c *Config) Redacted() *Config {
+	r := *c
+
+	// Replace admin token
+	if r.Admin.Token != "" {
+		r.Admin.Token = REDACTED
+	}
+
+	// Replace API keys, keeping their policies
+	// NOTE: Keys are numbered so that each policy is kept
+	if len(c.Auth.Keys) > 0 {
+		r.Auth.Keys = make(map[string]*KeyPolicy, len(c.Auth.Keys))
+
+		n := 0
+		for _, policy := range c.Auth.Keys {
+			n++
+			r.Auth.Keys[fmt.Sprintf("%s-%d", REDACTED, n)] = policy
+		}
+	}
+
+	return &r
+}
+
 // Init creates a new config instance and initializes it
 func Init() {
 	// Create new config instance
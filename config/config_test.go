@@ -157,6 +157,29 @@ var _ = Describe("config.go", func() {
 		})
 	})
 
+	Describe("`Redacted` method", func() {
+		BeforeEach(func() {
+			// Set secrets
+			c.Admin.Token = "secret-token"
+			c.Auth.Keys = map[string]*KeyPolicy{"secret-key": &KeyPolicy{MaxWidth: 100}}
+		})
+
+		It("Returns a copy of the configuration with secrets replaced", func() {
+			// Call method
+			r := c.Redacted()
+
+			// Verify return value
+			Expect(r.Admin.Token).To(Equal(REDACTED))
+			Expect(r.Auth.Keys).To(HaveLen(1))
+			Expect(r.Auth.Keys).To(Not(HaveKey("secret-key")))
+			Expect(r.Auth.Keys[REDACTED+"-1"].MaxWidth).To(Equal(100))
+
+			// Verify original configuration is unchanged
+			Expect(c.Admin.Token).To(Equal("secret-token"))
+			Expect(c.Auth.Keys).To(HaveKey("secret-key"))
+		})
+	})
+
 	Describe("`GetInstance` method", func() {
 		It("Returns an instance of the initialized configuration struct", func() {
 			// Call `Init` method to set up config
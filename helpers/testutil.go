@@ -8,12 +8,59 @@ import (
 	"net/http/httptest"
 )
 
+const (
+	// ETag returned by mock servers supporting conditional requests
+	MOCK_ETAG = `"mock-etag"`
+)
+
+var (
+	// A 1x1 GIF image returned by mock servers serving images
+	MockGif = []byte{
+		0x47, 0x49, 0x46, 0x38, 0x39, 0x61, 0x01, 0x00, 0x01, 0x00, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0xff, 0xff, 0xff, 0x21, 0xf9, 0x04, 0x01, 0x00, 0x00, 0x00, 0x00, 0x2c, 0x00, 0x00, 0x00, 0x00,
+		0x01, 0x00, 0x01, 0x00, 0x00, 0x02, 0x02, 0x44, 0x01, 0x00, 0x3b,
+	}
+)
+
 // GetMockServer returns a httptest server with the desired handler function
 // based on the key passed in
 func GetMockServer(key string) *httptest.Server {
 	var handler http.Handler
 
 	switch key {
+	case "conditional":
+		handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Respond with no body when the request's ETag matches
+			if r.Header.Get("If-None-Match") == MOCK_ETAG {
+				w.WriteHeader(304)
+				return
+			}
+
+			// Write headers and body
+			w.Header().Set("ETag", MOCK_ETAG)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(200)
+			fmt.Fprintln(w, `{"code":200,"conditional":true}`)
+		})
+	case "gif":
+		handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Write headers and body
+			w.Header().Set("Content-Type", "image/gif")
+			w.WriteHeader(200)
+			w.Write(MockGif)
+		})
+	case "request-id":
+		handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Write headers and body, echoing the request's ID
+			w.WriteHeader(200)
+			fmt.Fprint(w, r.Header.Get("X-Request-ID"))
+		})
+	case "traceparent":
+		handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Write headers and body, echoing the request's trace context
+			w.WriteHeader(200)
+			fmt.Fprint(w, r.Header.Get("traceparent"))
+		})
 	case "bad-request":
 		handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Write headers and body
@@ -26,6 +26,18 @@ var _ = Describe("testutil.go", func() {
 						BodySubstring: "{\"code\":400}",
 						StatusCode:    http.StatusBadRequest,
 					},
+					"conditional": &GetMockServerTestData{
+						BodySubstring: "{\"code\":200,\"conditional\":true}",
+						StatusCode:    http.StatusOK,
+					},
+					"request-id": &GetMockServerTestData{
+						BodySubstring: "",
+						StatusCode:    http.StatusOK,
+					},
+					"gif": &GetMockServerTestData{
+						BodySubstring: "GIF89a",
+						StatusCode:    http.StatusOK,
+					},
 					"default": &GetMockServerTestData{
 						BodySubstring: "{\"code\":200,\"foo\":\"bar\",\"test\":1234}",
 						StatusCode:    http.StatusOK,
@@ -2,6 +2,31 @@
 // that occur while processing images within this package
 package image
 
+import (
+	// Standard lib
+	"net/http"
+	"strings"
+
+	// Internal
+	"github.com/marksost/img/image/mutableimages"
+	"github.com/marksost/img/image/operations"
+	"github.com/marksost/img/image/utils"
+)
+
+const (
+	// Machine-readable codes describing errors that occur while processing images
+	// NOTE: These are part of the public API and should never change
+	ERROR_CODE_BOUNDS_EXCEEDED    = operations.ERROR_CODE_BOUNDS_EXCEEDED
+	ERROR_CODE_FORBIDDEN          = "forbidden"
+	ERROR_CODE_INVALID_IMAGE      = "invalid_image"
+	ERROR_CODE_INVALID_OPERATION  = operations.ERROR_CODE_INVALID_OPERATION
+	ERROR_CODE_OVER_CAPACITY      = "over_capacity"
+	ERROR_CODE_PROCESSING_FAILED  = operations.ERROR_CODE_PROCESSING_FAILED
+	ERROR_CODE_SOURCE_NOT_FOUND   = "source_not_found"
+	ERROR_CODE_SOURCE_UNAVAILABLE = "source_unavailable"
+	ERROR_CODE_UNSUPPORTED_FORMAT = "unsupported_format"
+)
+
 type (
 	// Struct representing an error that occurred during a image processing request
 	// NOTE: `ImageRequestError` satisfies the standard `error` interface,
@@ -9,8 +34,10 @@ type (
 	// A cast (like: `err.(*image.ImageRequestError)`) is needed
 	// to access non-nterface methods
 	ImageRequestError struct {
-		code int    // The error code to return for the error
-		str  string // The error string
+		code      int    // The error code to return for the error
+		errorCode string // A machine-readable code describing the error
+		param     string // The name of the request parameter that caused the error, if any
+		str       string // The error string
 	}
 )
 
@@ -20,6 +47,12 @@ func NewError(code int, str string) *ImageRequestError {
 	return &ImageRequestError{code: code, str: str}
 }
 
+// DefaultErrorCode returns a machine-readable error code for a HTTP status code,
+// formed from the status code's text (ex: "Not Found" becomes "not_found")
+func DefaultErrorCode(code int) string {
+	return strings.ToLower(strings.Replace(http.StatusText(code), " ", "_", -1))
+}
+
 // Code returns the internal `code` property of the error
 func (e *ImageRequestError) Code() int {
 	return e.code
@@ -29,3 +62,69 @@ func (e *ImageRequestError) Code() int {
 func (e *ImageRequestError) Error() string {
 	return e.str
 }
+
+// ErrorCode returns the internal `errorCode` property of the error,
+// or a default error code based on the error's HTTP status code if none was set
+func (e *ImageRequestError) ErrorCode() string {
+	if e.errorCode == "" {
+		return DefaultErrorCode(e.code)
+	}
+
+	return e.errorCode
+}
+
+// Param returns the internal `param` property of the error
+func (e *ImageRequestError) Param() string {
+	return e.param
+}
+
+// WithErrorCode sets the internal `errorCode` property of the error and returns the error
+func (e *ImageRequestError) WithErrorCode(errorCode string) *ImageRequestError {
+	e.errorCode = errorCode
+	return e
+}
+
+// WithParam sets the internal `param` property of the error and returns the error
+func (e *ImageRequestError) WithParam(param string) *ImageRequestError {
+	e.param = param
+	return e
+}
+
+// newDownloadError creates an error for a source image that couldn't be downloaded
+// NOTE: Failures of the origin are reported as a bad gateway, except for missing images
+func newDownloadError(err error) *ImageRequestError {
+	// Report missing images
+	if se, ok := err.(*utils.StatusError); ok &&
+		(se.StatusCode == http.StatusNotFound || se.StatusCode == http.StatusGone) {
+		return NewError(http.StatusNotFound, err.Error()).WithErrorCode(ERROR_CODE_SOURCE_NOT_FOUND)
+	}
+
+	return NewError(http.StatusBadGateway, err.Error()).WithErrorCode(ERROR_CODE_SOURCE_UNAVAILABLE)
+}
+
+// newMutableImageError creates an error for a source image that couldn't be read
+func newMutableImageError(err error, imageType string) *ImageRequestError {
+	// Report unsupported formats
+	if !mutableimages.IsSupportedType(imageType) {
+		return NewError(http.StatusUnsupportedMediaType, err.Error()).WithErrorCode(ERROR_CODE_UNSUPPORTED_FORMAT)
+	}
+
+	return NewError(http.StatusBadRequest, err.Error()).WithErrorCode(ERROR_CODE_INVALID_IMAGE)
+}
+
+// newProcessingError creates an error for an image operation that failed
+func newProcessingError(err error) *ImageRequestError {
+	// Use default code for unknown errors
+	oe, ok := err.(*operations.OperationError)
+	if !ok {
+		return NewError(http.StatusBadRequest, err.Error()).WithErrorCode(ERROR_CODE_INVALID_OPERATION)
+	}
+
+	// Report failures of the processing tools as server errors
+	code := http.StatusBadRequest
+	if oe.Code() == ERROR_CODE_PROCESSING_FAILED {
+		code = http.StatusInternalServerError
+	}
+
+	return NewError(code, err.Error()).WithErrorCode(oe.Code()).WithParam(oe.Operation())
+}
@@ -2,6 +2,13 @@
 package image
 
 import (
+	// Standard lib
+	"fmt"
+	"net/http"
+
+	// Internal
+	"github.com/marksost/img/image/utils"
+
 	// Third-party
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -50,5 +57,88 @@ var _ = Describe("error.go", func() {
 				Expect(str).To(Equal("This is a test error"))
 			})
 		})
+
+		Describe("`ErrorCode` method", func() {
+			Context("Without an error code set", func() {
+				It("Returns a default error code", func() {
+					// Verify return value
+					Expect(NewError(http.StatusNotFound, "").ErrorCode()).To(Equal("not_found"))
+				})
+			})
+
+			Context("With an error code set", func() {
+				It("Returns the internal `errorCode` property of the error", func() {
+					// Verify return value
+					Expect(err.WithErrorCode("foo").ErrorCode()).To(Equal("foo"))
+				})
+			})
+		})
+
+		Describe("`Param` method", func() {
+			It("Returns the internal `param` property of the error", func() {
+				// Verify return values
+				Expect(err.Param()).To(Equal(""))
+				Expect(err.WithParam("resize").Param()).To(Equal("resize"))
+			})
+		})
+	})
+
+	Describe("`DefaultErrorCode` method", func() {
+		It("Returns an error code formed from a status code's text", func() {
+			// Verify return values
+			Expect(DefaultErrorCode(http.StatusBadRequest)).To(Equal("bad_request"))
+			Expect(DefaultErrorCode(http.StatusTooManyRequests)).To(Equal("too_many_requests"))
+		})
+	})
+
+	Describe("`newDownloadError` method", func() {
+		It("Reports missing images as not found", func() {
+			// Call method
+			err := newDownloadError(&utils.StatusError{StatusCode: http.StatusNotFound})
+
+			// Verify return value
+			Expect(err.Code()).To(Equal(http.StatusNotFound))
+			Expect(err.ErrorCode()).To(Equal(ERROR_CODE_SOURCE_NOT_FOUND))
+		})
+
+		It("Reports other failures as unavailable", func() {
+			// Call method
+			err := newDownloadError(fmt.Errorf("Connection refused"))
+
+			// Verify return value
+			Expect(err.Code()).To(Equal(http.StatusBadGateway))
+			Expect(err.ErrorCode()).To(Equal(ERROR_CODE_SOURCE_UNAVAILABLE))
+		})
+	})
+
+	Describe("`newMutableImageError` method", func() {
+		It("Reports unsupported formats", func() {
+			// Call method
+			err := newMutableImageError(fmt.Errorf("Unsupported"), utils.DEFAULT_MIME_TYPE)
+
+			// Verify return value
+			Expect(err.Code()).To(Equal(http.StatusUnsupportedMediaType))
+			Expect(err.ErrorCode()).To(Equal(ERROR_CODE_UNSUPPORTED_FORMAT))
+		})
+
+		It("Reports unreadable images as invalid", func() {
+			// Call method
+			err := newMutableImageError(fmt.Errorf("Corrupt"), utils.GIF_MIME)
+
+			// Verify return value
+			Expect(err.Code()).To(Equal(http.StatusBadRequest))
+			Expect(err.ErrorCode()).To(Equal(ERROR_CODE_INVALID_IMAGE))
+		})
+	})
+
+	Describe("`newProcessingError` method", func() {
+		It("Reports unclassified errors as invalid operations", func() {
+			// Call method
+			err := newProcessingError(fmt.Errorf("Invalid"))
+
+			// Verify return value
+			Expect(err.Code()).To(Equal(http.StatusBadRequest))
+			Expect(err.ErrorCode()).To(Equal(ERROR_CODE_INVALID_OPERATION))
+		})
 	})
 })
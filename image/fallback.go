@@ -0,0 +1,112 @@
+// fallback contains all functionality around serving placeholder images
+// in place of source images that can't be downloaded
+package image
+
+import (
+	// Standard lib
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	// Internal
+	"github.com/marksost/img/config"
+
+	// Third-party
+	log "github.com/Sirupsen/logrus"
+)
+
+const (
+	// Custom header to be set containing the code of the error a placeholder image was served for
+	HEADER_FALLBACK = "X-Image-Fallback"
+)
+
+var (
+	// Map of placeholder image paths to their data
+	// NOTE: Placeholder images are read once and kept in memory
+	fallbacks = make(map[string][]byte)
+	// Mutex used to synchronize access to the placeholder images
+	fallbacksMutex sync.RWMutex
+)
+
+// useFallback replaces the source image with a placeholder image when one is configured,
+// returning a boolean indicating if a placeholder image is used
+func (i *Image) useFallback(err *ImageRequestError) bool {
+	// Get placeholder image path for the image's source
+	path := fallbackPath(i.source())
+	if path == "" {
+		return false
+	}
+
+	// Read placeholder image
+	data, readErr := readFallback(path)
+	if readErr != nil {
+		log.WithField("path", path).WithError(readErr).Error("Failed to read fallback image")
+		return false
+	}
+
+	// Use placeholder image in place of the source image
+	i.utils.Downloader.SetData(data)
+	i.fallbackErr = err
+
+	return true
+}
+
+// Status returns the status code the processed image should be served with
+// NOTE: Images served in place of ones that couldn't be downloaded use either the
+// configured fallback status code, or the status code of the original error
+func (i *Image) Status() int {
+	// Use default status code for source images
+	if i.fallbackErr == nil {
+		return http.StatusOK
+	}
+
+	// Use configured status code if set
+	if status := config.GetInstance().Images.Fallback.Status; status > 0 {
+		return status
+	}
+
+	return i.fallbackErr.Code()
+}
+
+// fallbackPath returns the path to the placeholder image used for a source,
+// or an empty string if no placeholder image is configured
+func fallbackPath(source string) string {
+	// Get fallback settings
+	settings := config.GetInstance().Images.Fallback
+
+	// Check for a source-specific placeholder image
+	for alias, path := range settings.Sources {
+		if strings.EqualFold(alias, source) {
+			return path
+		}
+	}
+
+	return settings.Default
+}
+
+// readFallback returns the data of a placeholder image, reading it from disk
+// the first time it's used
+func readFallback(path string) ([]byte, error) {
+	// Check for previously read data
+	fallbacksMutex.RLock()
+	data, ok := fallbacks[path]
+	fallbacksMutex.RUnlock()
+
+	if ok {
+		return data, nil
+	}
+
+	// Read data from disk
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Store data for later use
+	fallbacksMutex.Lock()
+	fallbacks[path] = data
+	fallbacksMutex.Unlock()
+
+	return data, nil
+}
@@ -0,0 +1,143 @@
+// Tests the fallback.go file
+package image
+
+import (
+	// Standard lib
+	"net/http"
+	"strings"
+
+	// Internal
+	"github.com/marksost/img/config"
+	"github.com/marksost/img/helpers"
+	"github.com/marksost/img/image/operations"
+	"github.com/marksost/img/image/utils"
+
+	// Third-party
+	"github.com/kataras/iris"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/valyala/fasthttp"
+)
+
+var _ = Describe("fallback.go", func() {
+	var (
+		// Mock image to test
+		i *Image
+		// Host of the mock server images are requested from
+		host string
+	)
+
+	BeforeEach(func() {
+		// Initalize config instance
+		config.Init()
+
+		// Create mock server
+		server := helpers.GetMockServer("bad-request")
+		host = strings.TrimPrefix(server.URL, "http://")
+
+		// Create mock image
+		i = NewImage(&iris.Context{
+			RequestCtx: &fasthttp.RequestCtx{},
+		})
+		i.utils = &ImageUtils{
+			Downloader:          utils.NewDownloader(host + "/image.gif"),
+			OperationController: operations.NewOperationController(nil),
+		}
+	})
+
+	Describe("`Process` method", func() {
+		Context("Without a placeholder image configured", func() {
+			It("Returns the download error", func() {
+				// Call method
+				err := i.Process()
+
+				// Verify return value
+				Expect(err).To(HaveOccurred())
+				Expect(err.(*ImageRequestError).Code()).To(Equal(http.StatusBadGateway))
+			})
+		})
+
+		Context("With a placeholder image configured", func() {
+			BeforeEach(func() {
+				// Set placeholder image
+				config.GetInstance().Images.Fallback.Default = "../test/images/1x1.gif"
+			})
+
+			It("Processes the placeholder image", func() {
+				// Call method
+				err := i.Process()
+
+				// Verify return value
+				Expect(err).To(Not(HaveOccurred()))
+				Expect(i.MimeType()).To(Equal(utils.GIF_MIME))
+				Expect(i.fallbackErr.ErrorCode()).To(Equal(ERROR_CODE_SOURCE_UNAVAILABLE))
+			})
+		})
+	})
+
+	Describe("`Status` method", func() {
+		Context("Without a placeholder image used", func() {
+			It("Returns a 200 status code", func() {
+				// Verify return value
+				Expect(i.Status()).To(Equal(http.StatusOK))
+			})
+		})
+
+		Context("With a placeholder image used", func() {
+			BeforeEach(func() {
+				// Set used placeholder image
+				i.fallbackErr = NewError(http.StatusNotFound, "Not found")
+			})
+
+			It("Returns the original error's status code", func() {
+				// Verify return value
+				Expect(i.Status()).To(Equal(http.StatusNotFound))
+			})
+
+			It("Returns the configured status code when set", func() {
+				// Set status code
+				config.GetInstance().Images.Fallback.Status = http.StatusOK
+
+				// Verify return value
+				Expect(i.Status()).To(Equal(http.StatusOK))
+			})
+		})
+	})
+
+	Describe("`fallbackPath` method", func() {
+		BeforeEach(func() {
+			// Set placeholder images
+			config.GetInstance().Images.Fallback.Default = "default.gif"
+			config.GetInstance().Images.Fallback.Sources = map[string]string{"foo.com": "foo.gif"}
+		})
+
+		It("Returns the placeholder image path for a source", func() {
+			// Verify return values
+			Expect(fallbackPath("FOO.com")).To(Equal("foo.gif"))
+			Expect(fallbackPath("bar.com")).To(Equal("default.gif"))
+		})
+	})
+
+	Describe("`readFallback` method", func() {
+		Context("With a missing file", func() {
+			It("Returns an error", func() {
+				// Call method
+				_, err := readFallback("../test/images/missing.gif")
+
+				// Verify return value
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("With an existing file", func() {
+			It("Returns the file's data", func() {
+				// Call method
+				data, err := readFallback("../test/images/1x1.gif")
+
+				// Verify return value
+				Expect(err).To(Not(HaveOccurred()))
+				Expect(len(data)).To(Not(Equal(0)))
+			})
+		})
+	})
+})
@@ -2,40 +2,64 @@ package image
 
 import (
 	// Standard lib
+	"fmt"
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	// Internal
+	"github.com/marksost/img/config"
 	"github.com/marksost/img/helpers"
 	"github.com/marksost/img/image/mutableimages"
 	"github.com/marksost/img/image/operations"
 	"github.com/marksost/img/image/utils"
 
 	// Third-party
+	log "github.com/Sirupsen/logrus"
 	"github.com/kataras/iris"
 )
 
 const (
+	// URL param used to request debugging details about an image's processing
+	DEBUG_PARAM = "debug"
+
 	// Custom header to be set containing the source dimensions for the image
 	HEADER_ANIMATED = "X-Animated"
+	// Custom header to be set containing the time taken to play all frames of the image once, in milliseconds
+	HEADER_DURATION = "X-Animation-Duration"
 	// Custom header to be set containing the source dimensions for the image
 	HEADER_FINAL_DIMENSIONS = "X-Final-Image-Dimensions"
+	// Custom header to be set containing the number of frames in the image
+	HEADER_FRAMES = "X-Frames"
+	// Custom header to be set containing the number of times the image's frames are played (0 for forever)
+	HEADER_LOOP_COUNT = "X-Loop-Count"
 	// Custom header to be set containing the MIME type of the image
 	HEADER_MIME = "X-MIME-Type"
 	// Custom header to be set containing the operations performed during processing
 	HEADER_OPERATIONS_PERFORMED = "X-Operations-Performed"
+	// Header to be set containing a breakdown of the time taken to process the image
+	HEADER_SERVER_TIMING = "Server-Timing"
 	// Custom header to be set containing the source dimensions for the image
 	HEADER_SOURCE_DIMENSIONS = "X-Source-Image-Dimensions"
 	// Custom header to be set containing the source URL for the image
 	HEADER_SOURCE_URL = "X-Image-Source"
 )
 
+var (
+	// Limiters used to cap the number of GIF and static images processed at once
+	// NOTE: Nil limiters place no limits on concurrency
+	gifLimiter, staticLimiter *utils.Limiter
+)
+
 type (
 	// Struct representing a single image to be processed from a HTTP request
 	Image struct {
-		ctx   *iris.Context // The request context this image relates to
-		utils *ImageUtils   // A collection of utilities used while processing a request
+		ctx          *iris.Context      // The request context this image relates to
+		decodeTime   time.Duration      // Time taken to decode the image
+		downloadTime time.Duration      // Time taken to download the image
+		fallbackErr  *ImageRequestError // The error a placeholder image is being served for, if any
+		utils        *ImageUtils        // A collection of utilities used while processing a request
 	}
 	// Struct representing an `Image` struct's utilities used while processing a request
 	ImageUtils struct {
@@ -50,42 +74,114 @@ type (
 
 /* Begin main public functionality methods */
 
+// Init sets up package-level utilities shared by all image requests,
+// such as the source image cache and processing limiters
+func Init() {
+	// Get configuration instance
+	c := config.GetInstance()
+
+	// Set up source image cache
+	utils.InitSourceCache()
+
+	// Set up GIF command limits
+	mutableimages.InitGifCommand()
+
+	// Set up processing limiters
+	gifLimiter = utils.NewLimiter(c.Images.Concurrency.Gif, c.Images.Concurrency.Queue)
+	staticLimiter = utils.NewLimiter(c.Images.Concurrency.Static, c.Images.Concurrency.Queue)
+}
+
 // NewImage creates a new `Image` and returns it
 func NewImage(ctx *iris.Context) *Image {
-	// Create and return new image with context set from input
-	return &Image{
+	// Create new image with context set from input
+	i := &Image{
 		ctx: ctx,
 		utils: &ImageUtils{
 			Downloader:          utils.NewDownloader(ctx.Param("img")),
 			OperationController: operations.NewOperationController(ctx.GetRequestCtx().URI().QueryString()),
 		},
 	}
+
+	// Carry the request's logger, ID and span through processing
+	i.utils.Downloader.SetLogger(Logger(ctx))
+	i.utils.Downloader.SetRequestID(RequestID(ctx))
+	i.utils.Downloader.SetSpan(RequestSpan(ctx))
+	i.utils.OperationController.SetLogger(Logger(ctx))
+	i.utils.OperationController.SetSpan(RequestSpan(ctx))
+
+	return i
 }
 
 // Process is used to handle a single image processing request
 // It will download the image, process it based on request parameters
 // and return the result
 func (i *Image) Process() error {
-	// Set error for use in this method
-	var err error
+	var (
+		// Set error for use in this method
+		err error
+		// Times at which the image finished downloading and processing
+		downloaded, processed time.Time
+		// Time at which processing started
+		start = time.Now()
+	)
 
-	// Use downloader utility to download image from URL
+	// Add details about the image to the request's logs once finished
+	defer func() {
+		i.addLogFields(start, downloaded, processed)
+	}()
+
+	// Verify request is allowed by it's API key's policy
+	if err = i.authorizeRequest(); err != nil {
+		return err
+	}
+
+	// Use downloader utility to download image from URL,
+	// falling back to a placeholder image when configured
+	downloadStart := time.Now()
 	if err = i.utils.Downloader.Download(); err != nil {
-		// Return bad request error
-		return NewError(http.StatusBadRequest, err.Error())
+		if ire := newDownloadError(err); !i.useFallback(ire) {
+			return ire
+		}
 	}
 
-	// Create mutable image object to process
+	// Store download time
+	downloaded = time.Now()
+	i.downloadTime = downloaded.Sub(downloadStart)
+
+	// Wait for a processing slot for the image's type
+	limiter := i.limiter()
+	if err = limiter.Acquire(); err != nil {
+		// Return service unavailable error
+		return NewError(http.StatusServiceUnavailable, err.Error()).WithErrorCode(ERROR_CODE_OVER_CAPACITY)
+	}
+
+	// Release slot after processing
+	defer limiter.Release()
+
+	// Create mutable image object to process, tracing the decode
+	span := StartSpan(i.ctx, "decode")
+	span.SetAttribute("mime-type", i.MimeType())
+	decodeStart := time.Now()
 	i.utils.MutableImage, err = mutableimages.NewMutableImage(i.RawData(), i.MimeType())
+	i.decodeTime = time.Since(decodeStart)
+	span.SetError(err)
+	span.End()
+
 	if err != nil {
-		// Return bad request error
-		return NewError(http.StatusBadRequest, err.Error())
+		return newMutableImageError(err, i.MimeType())
 	}
 
 	// Process mutable image, returning an error if one occurred
 	if err = i.utils.OperationController.Process(&i.utils.MutableImage); err != nil {
-		// Return bad request error
-		return NewError(http.StatusBadRequest, err.Error())
+		return newProcessingError(err)
+	}
+
+	// Store processing time
+	processed = time.Now()
+
+	// Verify output is allowed by the request's API key's policy
+	if err = i.authorizeOutput(); err != nil {
+		return err
 	}
 
 	// Set custom headers
@@ -114,6 +210,17 @@ func (i *Image) MimeType() string {
 	return i.utils.Downloader.MimeType()
 }
 
+// OutputMimeType returns a string representing the MIME type of the processed image,
+// which may differ from the downloaded image when it's format was changed
+// NOTE: Falls back to the MIME type of the downloaded image before processing
+func (i *Image) OutputMimeType() string {
+	if i.utils.MutableImage != nil && i.utils.MutableImage.Img().ImageType != "" {
+		return i.utils.MutableImage.Img().ImageType
+	}
+
+	return i.MimeType()
+}
+
 // RawData returns a byte slice representing the raw data from the downloaded image
 // NOTE: Proxies the call to this image's downloader utility
 func (i *Image) RawData() []byte {
@@ -130,14 +237,63 @@ func (i *Image) Url() *url.URL {
 
 /* Begin utility methods */
 
+// addLogFields adds details about the image to the request's logs, including
+// how long downloading and processing the image took when they were completed
+func (i *Image) addLogFields(start, downloaded, processed time.Time) {
+	// Form fields
+	fields := log.Fields{
+		"cache-status": i.utils.Downloader.CacheStatus(),
+		"operations":   strings.Join(i.utils.OperationController.Names(), ","),
+		"source":       i.Url().String(),
+		"source-bytes": len(i.RawData()),
+	}
+
+	if !downloaded.IsZero() {
+		fields["download-ms"] = helpers.Float642String(downloaded.Sub(start).Seconds() * 1000)
+	}
+
+	if !processed.IsZero() {
+		fields["output-bytes"] = len(i.Data())
+		fields["process-ms"] = helpers.Float642String(processed.Sub(downloaded).Seconds() * 1000)
+	}
+
+	AddLogFields(i.ctx, fields)
+}
+
+// limiter returns the limiter used to cap the number of images
+// of the same type as this image processed at once
+func (i *Image) limiter() *utils.Limiter {
+	// NOTE: Animated PNGs may be processed frame by frame, the same as GIFs,
+	// depending on how they're handled
+	if mutableimages.IsProcessedAsGif(i.MimeType()) {
+		return gifLimiter
+	}
+
+	return staticLimiter
+}
+
+// source returns the source alias (the host) the image is requested from
+func (i *Image) source() string {
+	if u := i.Url(); u != nil {
+		return u.Host
+	}
+
+	return ""
+}
+
 // setCustomHeaders is used to set headers with values specific to the image
 // on the response
 func (i *Image) setCustomHeaders() {
 	var (
+		// Processable image to read image details from
+		img = i.utils.MutableImage.Img()
 		// Map of headers to set
 		headers map[string]string = map[string]string{
-			HEADER_ANIMATED:   helpers.Bool2String(i.utils.MutableImage.Img().Animated),
-			HEADER_MIME:       i.MimeType(),
+			HEADER_ANIMATED:   helpers.Bool2String(img.Animated),
+			HEADER_DURATION:   helpers.Int642String(int64(img.Duration / time.Millisecond)),
+			HEADER_FRAMES:     helpers.Int2String(img.Frames),
+			HEADER_LOOP_COUNT: helpers.Int2String(img.LoopCount),
+			HEADER_MIME:       i.OutputMimeType(),
 			HEADER_SOURCE_URL: i.Url().String(),
 		}
 		// Slice of operations converated to strings
@@ -157,6 +313,16 @@ func (i *Image) setCustomHeaders() {
 	// Set operations header
 	headers[HEADER_OPERATIONS_PERFORMED] = strings.Join(ops, ", ")
 
+	// Set fallback header if needed
+	if i.fallbackErr != nil {
+		headers[HEADER_FALLBACK] = i.fallbackErr.ErrorCode()
+	}
+
+	// Set processing time breakdown if needed
+	if i.serverTimingEnabled() {
+		headers[HEADER_SERVER_TIMING] = i.serverTiming()
+	}
+
 	// Loop through headers, setting each in turn
 	for k, v := range headers {
 		i.ctx.SetHeader(k, v)
@@ -164,7 +330,46 @@ func (i *Image) setCustomHeaders() {
 
 	// Set source and final dimensions
 	i.setDimensionHeader(HEADER_FINAL_DIMENSIONS, i.utils.MutableImage.GetWidth(), i.utils.MutableImage.GetHeight())
-	i.setDimensionHeader(HEADER_SOURCE_DIMENSIONS, i.utils.MutableImage.Img().SourceWidth, i.utils.MutableImage.Img().SourceHeight)
+	i.setDimensionHeader(HEADER_SOURCE_DIMENSIONS, img.SourceWidth, img.SourceHeight)
+}
+
+// serverTiming returns a Server-Timing header value containing the time taken
+// to download and decode the image, and to process each operation
+// NOTE: Stages processed more than once are numbered after the first,
+// so that each metric has a unique name (ex: "resize, resize-2")
+func (i *Image) serverTiming() string {
+	// Form stage timings
+	timings := []operations.OperationTiming{
+		{Duration: i.downloadTime, Name: "download"},
+		{Duration: i.decodeTime, Name: "decode"},
+	}
+	timings = append(timings, i.utils.OperationController.Timings()...)
+
+	// Loop through timings, forming entries
+	counts := make(map[string]int)
+	entries := make([]string, 0, len(timings))
+	for _, timing := range timings {
+		name := timing.Name
+
+		counts[name]++
+		if counts[name] > 1 {
+			name += "-" + helpers.Int2String(counts[name])
+		}
+
+		entries = append(entries, fmt.Sprintf("%s;dur=%.2f", name, timing.Duration.Seconds()*1000))
+	}
+
+	return strings.Join(entries, ", ")
+}
+
+// serverTimingEnabled returns a boolean indicating if a Server-Timing header should be set,
+// either for all images via configuration, or for a single debug request
+func (i *Image) serverTimingEnabled() bool {
+	if c := config.GetInstance(); c != nil && c.Images.ServerTiming {
+		return true
+	}
+
+	return i.ctx.URLParam(DEBUG_PARAM) == "true"
 }
 
 // setDimensionHeader sets a header representing a specific dimension pattern of "WIDTHxHEIGHT"
@@ -5,9 +5,13 @@ import (
 	// Standard lib
 	"io/ioutil"
 	"path"
+	"strings"
+	"time"
 
 	// Internal
+	"github.com/marksost/img/config"
 	"github.com/marksost/img/image/mutableimages"
+	"github.com/marksost/img/image/operations"
 	"github.com/marksost/img/image/utils"
 
 	// Third-party
@@ -17,6 +21,25 @@ import (
 	"github.com/valyala/fasthttp"
 )
 
+type (
+	// Struct representing an operation that does nothing, for tests
+	mockOperation struct {
+		name string
+	}
+)
+
+// Mock operation's Process method
+func (o *mockOperation) Process(mi *mutableimages.MutableImage) error { return nil }
+
+// Mock operation's Validate method
+func (o *mockOperation) Validate() error { return nil }
+
+// Mock operation's Name method
+func (o *mockOperation) Name() string { return o.name }
+
+// Mock operation's String method
+func (o *mockOperation) String() string { return "" }
+
 var _ = Describe("image.go", func() {
 	var (
 		// Mock iris context to use within tests
@@ -35,6 +58,31 @@ var _ = Describe("image.go", func() {
 		i = NewImage(ctx)
 	})
 
+	Describe("`Init` method", func() {
+		BeforeEach(func() {
+			// Initalize config instance
+			config.Init()
+
+			// Set concurrency limits
+			config.GetInstance().Images.Concurrency.Gif = 0
+			config.GetInstance().Images.Concurrency.Static = 2
+		})
+
+		AfterEach(func() {
+			// Reset limiters
+			gifLimiter, staticLimiter = nil, nil
+		})
+
+		It("Sets up processing limiters", func() {
+			// Call method
+			Init()
+
+			// Verify limiters
+			Expect(gifLimiter).To(BeNil())
+			Expect(staticLimiter).To(Not(BeNil()))
+		})
+	})
+
 	Describe("`NewImage` method", func() {
 		It("Returns a valid image", func() {
 			// Call method
@@ -94,6 +142,29 @@ var _ = Describe("image.go", func() {
 			})
 		})
 
+		Describe("`OutputMimeType` method", func() {
+			Context("Before the image is processed", func() {
+				It("Returns the MIME type of the downloaded image", func() {
+					// Verify return value
+					Expect(i.OutputMimeType()).To(Equal(utils.DEFAULT_MIME_TYPE))
+				})
+			})
+
+			Context("After the image is processed", func() {
+				It("Returns the MIME type of the processed image", func() {
+					// Set processed image
+					data, err := ioutil.ReadFile(path.Join("../test/images/1x1.gif"))
+					Expect(err).To(Not(HaveOccurred()))
+
+					i.utils.MutableImage, err = mutableimages.NewMutableImage(data, utils.GIF_MIME)
+					Expect(err).To(Not(HaveOccurred()))
+
+					// Verify return value
+					Expect(i.OutputMimeType()).To(Equal(utils.GIF_MIME))
+				})
+			})
+		})
+
 		Describe("`RawData` method", func() {
 			It("Returns an empty byte slice", func() {
 				// Call method
@@ -114,4 +185,88 @@ var _ = Describe("image.go", func() {
 			})
 		})
 	})
+
+	Describe("Image utility methods", func() {
+		Describe("`serverTiming` method", func() {
+			BeforeEach(func() {
+				// Create mi
+				data, err := ioutil.ReadFile(path.Join("../test/images/1x1.gif"))
+				if err != nil {
+					panic("Error reading image. Tests cannot continue. " + err.Error())
+				}
+
+				mi, err := mutableimages.NewMutableImage(data, utils.GIF_MIME)
+				if err != nil {
+					panic("Error creating mutable image. Tests cannot continue. " + err.Error())
+				}
+
+				// Process operations, with one run twice
+				oc := operations.NewOperationController(nil)
+				oc.Operations = []operations.Operation{
+					&mockOperation{name: operations.OPERATION_NAME_RESIZE},
+					&mockOperation{name: operations.OPERATION_NAME_CROP},
+					&mockOperation{name: operations.OPERATION_NAME_RESIZE},
+				}
+				oc.QualityOperation = &mockOperation{name: operations.OPERATION_NAME_QUALITY}
+				oc.Process(&mi)
+
+				// Set utilities and timings
+				i.utils.OperationController = oc
+				i.downloadTime = 12340 * time.Microsecond
+				i.decodeTime = 5 * time.Millisecond
+			})
+
+			It("Returns the time taken by each processing stage", func() {
+				// Call method
+				entries := strings.Split(i.serverTiming(), ", ")
+
+				// Verify return value
+				Expect(entries).To(HaveLen(6))
+				Expect(entries[0]).To(Equal("download;dur=12.34"))
+				Expect(entries[1]).To(Equal("decode;dur=5.00"))
+				Expect(entries[2]).To(MatchRegexp(`^resize;dur=\d+\.\d{2}$`))
+				Expect(entries[3]).To(MatchRegexp(`^crop;dur=\d+\.\d{2}$`))
+				Expect(entries[4]).To(MatchRegexp(`^resize-2;dur=\d+\.\d{2}$`))
+				Expect(entries[5]).To(MatchRegexp(`^encode;dur=\d+\.\d{2}$`))
+			})
+		})
+
+		Describe("`serverTimingEnabled` method", func() {
+			BeforeEach(func() {
+				// Initalize config instance
+				config.Init()
+			})
+
+			Context("Without a debug param or config flag", func() {
+				It("Returns false", func() {
+					// Verify return value
+					Expect(i.serverTimingEnabled()).To(BeFalse())
+				})
+			})
+
+			Context("With a debug param", func() {
+				BeforeEach(func() {
+					// Set debug param
+					ctx.Request.SetRequestURI("/foo.com/image.jpg?debug=true")
+				})
+
+				It("Returns true", func() {
+					// Verify return value
+					Expect(i.serverTimingEnabled()).To(BeTrue())
+				})
+			})
+
+			Context("With the config flag set", func() {
+				BeforeEach(func() {
+					// Set config flag
+					config.GetInstance().Images.ServerTiming = true
+				})
+
+				It("Returns true", func() {
+					// Verify return value
+					Expect(i.serverTimingEnabled()).To(BeTrue())
+				})
+			})
+		})
+	})
 })
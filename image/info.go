@@ -0,0 +1,84 @@
+// info contains all functionality around reporting metadata about
+// a source image without processing it
+package image
+
+import (
+	// Standard lib
+	"net/http"
+	"strings"
+	"time"
+
+	// Internal
+	"github.com/marksost/img/image/mutableimages"
+	"github.com/marksost/img/image/utils"
+)
+
+type (
+	// Struct representing metadata about a single source image
+	ImageInfo struct {
+		Animated   bool                   `json:"animated"`    // Whether the image is animated or not
+		ColorSpace string                 `json:"color-space"` // The color space of the image
+		DurationMs int64                  `json:"duration-ms"` // The time taken to play all frames of the image once, in milliseconds
+		Exif       map[string]interface{} `json:"exif"`        // EXIF metadata of the image, if any
+		Format     string                 `json:"format"`      // The format of the image (ex: "jpeg")
+		Frames     int                    `json:"frames"`      // The number of frames in the image
+		Height     int64                  `json:"height"`      // The height of the image
+		LoopCount  int                    `json:"loop-count"`  // The number of times the image's frames are played (0 for forever)
+		MimeType   string                 `json:"mime-type"`   // The MIME type of the image
+		Size       int                    `json:"size"`        // The size of the image's data, in bytes
+		Source     string                 `json:"source"`      // The URL the image was downloaded from
+		Width      int64                  `json:"width"`       // The width of the image
+	}
+)
+
+// Info is used to handle a single image metadata request
+// It will download the image and return metadata about it, without processing it
+func (i *Image) Info() (*ImageInfo, error) {
+	// Set error for use in this method
+	var err error
+
+	// Verify request is allowed by it's API key's policy
+	if err = i.authorizeRequest(); err != nil {
+		return nil, err
+	}
+
+	// Use downloader utility to download image from URL
+	if err = i.utils.Downloader.Download(); err != nil {
+		return nil, newDownloadError(err)
+	}
+
+	// Wait for a processing slot for the image's type
+	// NOTE: Needed, since images are fully decoded to read their metadata
+	limiter := i.limiter()
+	if err = limiter.Acquire(); err != nil {
+		// Return service unavailable error
+		return nil, NewError(http.StatusServiceUnavailable, err.Error()).WithErrorCode(ERROR_CODE_OVER_CAPACITY)
+	}
+
+	// Release slot after reading metadata
+	defer limiter.Release()
+
+	// Create mutable image object to read metadata from
+	i.utils.MutableImage, err = mutableimages.NewMutableImage(i.RawData(), i.MimeType())
+	if err != nil {
+		return nil, newMutableImageError(err, i.MimeType())
+	}
+
+	// Form metadata
+	img := i.utils.MutableImage.Img()
+
+	return &ImageInfo{
+		Animated:   img.Animated,
+		ColorSpace: i.utils.MutableImage.ColorSpace(),
+		DurationMs: int64(img.Duration / time.Millisecond),
+		Exif:       utils.ReadExif(i.RawData()),
+		Format:     strings.TrimPrefix(i.MimeType(), "image/"),
+		Frames:     img.Frames,
+		Height:     img.SourceHeight,
+		LoopCount:  img.LoopCount,
+		MimeType:   i.MimeType(),
+		Size:       len(i.RawData()),
+		Source:     i.Url().String(),
+		Width:      img.SourceWidth,
+	}, nil
+}
@@ -0,0 +1,92 @@
+// Tests the info.go file
+package image
+
+import (
+	// Standard lib
+	"net/http"
+	"strings"
+
+	// Internal
+	"github.com/marksost/img/config"
+	"github.com/marksost/img/helpers"
+	"github.com/marksost/img/image/operations"
+	"github.com/marksost/img/image/utils"
+
+	// Third-party
+	"github.com/kataras/iris"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/valyala/fasthttp"
+)
+
+var _ = Describe("info.go", func() {
+	var (
+		// Mock image to test
+		i *Image
+	)
+
+	BeforeEach(func() {
+		// Initalize config instance
+		config.Init()
+
+		// Create mock image
+		i = NewImage(&iris.Context{
+			RequestCtx: &fasthttp.RequestCtx{},
+		})
+	})
+
+	Describe("`Info` method", func() {
+		Context("When the image can't be downloaded", func() {
+			BeforeEach(func() {
+				// Create mock server
+				server := helpers.GetMockServer("bad-request")
+
+				// Set new utility structs to ensure predictable values
+				i.utils = &ImageUtils{
+					Downloader:          utils.NewDownloader(strings.TrimPrefix(server.URL, "http://")),
+					OperationController: operations.NewOperationController(nil),
+				}
+			})
+
+			It("Returns a source unavailable error", func() {
+				// Call method
+				_, err := i.Info()
+
+				// Verify return value
+				Expect(err).To(HaveOccurred())
+				Expect(err.(*ImageRequestError).Code()).To(Equal(http.StatusBadGateway))
+				Expect(err.(*ImageRequestError).ErrorCode()).To(Equal(ERROR_CODE_SOURCE_UNAVAILABLE))
+			})
+		})
+
+		Context("When the image can be downloaded", func() {
+			BeforeEach(func() {
+				// Create mock server
+				server := helpers.GetMockServer("gif")
+
+				// Set new utility structs to ensure predictable values
+				i.utils = &ImageUtils{
+					Downloader:          utils.NewDownloader(strings.TrimPrefix(server.URL, "http://")),
+					OperationController: operations.NewOperationController(nil),
+				}
+			})
+
+			It("Returns metadata about the image", func() {
+				// Call method
+				info, err := i.Info()
+
+				// Verify return value
+				Expect(err).To(Not(HaveOccurred()))
+				Expect(info.Animated).To(BeFalse())
+				Expect(info.ColorSpace).To(Equal("srgb"))
+				Expect(info.Exif).To(BeNil())
+				Expect(info.Format).To(Equal("gif"))
+				Expect(info.Frames).To(Equal(1))
+				Expect(info.Height).To(BeEquivalentTo(1))
+				Expect(info.MimeType).To(Equal(utils.GIF_MIME))
+				Expect(info.Size).To(Equal(len(helpers.MockGif)))
+				Expect(info.Width).To(BeEquivalentTo(1))
+			})
+		})
+	})
+})
@@ -0,0 +1,39 @@
+// logging contains all functionality around the per-request logger, which carries
+// a request's ID and details about it's image through processing
+package image
+
+import (
+	// Third-party
+	log "github.com/Sirupsen/logrus"
+	"github.com/kataras/iris"
+)
+
+const (
+	// Key the request's logger is stored under in the request context
+	LOGGER_CONTEXT_KEY = "logger"
+	// Key the request's ID is stored under in the request context
+	REQUEST_ID_CONTEXT_KEY = "request-id"
+)
+
+// Logger returns the logger for a request, which includes all fields added to it
+// while handling the request, or a default logger if none was set
+func Logger(c *iris.Context) *log.Entry {
+	if entry, ok := c.Get(LOGGER_CONTEXT_KEY).(*log.Entry); ok {
+		return entry
+	}
+
+	return log.NewEntry(log.StandardLogger())
+}
+
+// AddLogFields adds fields to a request's logger, so that they're included
+// in all later logs for the request, including it's access log
+func AddLogFields(c *iris.Context, fields log.Fields) {
+	c.Set(LOGGER_CONTEXT_KEY, Logger(c).WithFields(fields))
+}
+
+// RequestID returns the ID of a request, or an empty string if none was set
+func RequestID(c *iris.Context) string {
+	id, _ := c.Get(REQUEST_ID_CONTEXT_KEY).(string)
+
+	return id
+}
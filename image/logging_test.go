@@ -0,0 +1,76 @@
+// Tests the logging.go file
+package image
+
+import (
+	// Third-party
+	log "github.com/Sirupsen/logrus"
+	"github.com/kataras/iris"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/valyala/fasthttp"
+)
+
+var _ = Describe("logging.go", func() {
+	var (
+		// Mock iris context to use within tests
+		ctx *iris.Context
+	)
+
+	BeforeEach(func() {
+		// Create mock context
+		ctx = &iris.Context{
+			RequestCtx: &fasthttp.RequestCtx{},
+		}
+	})
+
+	Describe("`Logger` method", func() {
+		Context("Without a logger set", func() {
+			It("Returns a default logger", func() {
+				// Call method
+				entry := Logger(ctx)
+
+				// Verify return value
+				Expect(entry).To(Not(BeNil()))
+				Expect(entry.Data).To(BeEmpty())
+			})
+		})
+
+		Context("With a logger set", func() {
+			BeforeEach(func() {
+				// Set logger
+				ctx.Set(LOGGER_CONTEXT_KEY, log.WithField("request-id", "foo"))
+			})
+
+			It("Returns the logger", func() {
+				// Verify return value
+				Expect(Logger(ctx).Data).To(HaveKeyWithValue("request-id", "foo"))
+			})
+		})
+	})
+
+	Describe("`AddLogFields` method", func() {
+		BeforeEach(func() {
+			// Set logger
+			ctx.Set(LOGGER_CONTEXT_KEY, log.WithField("request-id", "foo"))
+		})
+
+		It("Adds fields to the request's logger", func() {
+			// Call method
+			AddLogFields(ctx, log.Fields{"source": "bar"})
+
+			// Verify logger
+			Expect(Logger(ctx).Data).To(HaveKeyWithValue("request-id", "foo"))
+			Expect(Logger(ctx).Data).To(HaveKeyWithValue("source", "bar"))
+		})
+	})
+
+	Describe("`RequestID` method", func() {
+		It("Returns the request's ID, or an empty string if none was set", func() {
+			// Verify return values
+			Expect(RequestID(ctx)).To(Equal(""))
+
+			ctx.Set(REQUEST_ID_CONTEXT_KEY, "foo")
+			Expect(RequestID(ctx)).To(Equal("foo"))
+		})
+	})
+})
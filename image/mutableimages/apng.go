@@ -0,0 +1,360 @@
+// apng contains all functionality around reading animated PNG images,
+// and converting them to GIFs so that all of their frames can be processed
+// See https://wiki.mozilla.org/APNG_Specification for more information
+package mutableimages
+
+import (
+	// Standard lib
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/png"
+
+	// Internal
+	"github.com/marksost/img/config"
+	"github.com/marksost/img/image/utils"
+)
+
+const (
+	// Modes animated PNGs can be handled with
+	APNG_MODE_GIF         = "gif"
+	APNG_MODE_PASSTHROUGH = "passthrough"
+	APNG_MODE_STATIC      = "static"
+	APNG_MODE_WEBP        = "webp"
+
+	// Methods used to dispose of an animated PNG frame before drawing the next
+	APNG_DISPOSE_NONE       = 0
+	APNG_DISPOSE_BACKGROUND = 1
+	APNG_DISPOSE_PREVIOUS   = 2
+	// Method used to draw an animated PNG frame over the previous frame
+	// NOTE: Frames are otherwise drawn in place of the previous frame
+	APNG_BLEND_OVER = 1
+
+	// The signature all PNGs start with
+	PNG_SIGNATURE = "\x89PNG\r\n\x1a\n"
+)
+
+type (
+	// Struct representing the structure of an animated PNG
+	apngImage struct {
+		frames []*apngFrame // The frames of the image
+		header []byte       // The data of the image's header chunk
+		plays  int          // The number of times the image's frames are played (0 for forever)
+		shared [][]byte     // Chunks shared by all frames, such as palettes
+		width  int          // The width of the image
+		height int          // The height of the image
+	}
+	// Struct representing a single frame of an animated PNG
+	apngFrame struct {
+		blend   byte     // The method used to draw the frame
+		data    [][]byte // The compressed image data of the frame
+		delay   int      // The delay after the frame, in hundredths of a second
+		dispose byte     // The method used to dispose of the frame
+		x, y    int      // The offset of the frame within the canvas
+		width   int      // The width of the frame
+		height  int      // The height of the frame
+	}
+)
+
+// apngMode returns the configured mode animated PNGs are handled with
+func apngMode() string {
+	if c := config.GetInstance(); c != nil && c.Images.Apng.Mode != "" {
+		return c.Images.Apng.Mode
+	}
+
+	return APNG_MODE_STATIC
+}
+
+// IsProcessedAsGif returns a boolean indicating if images of a type are processed
+// as GIFs, frame by frame
+// NOTE: Animated PNGs are only converted to GIFs in some modes
+func IsProcessedAsGif(imageType string) bool {
+	switch imageType {
+	case utils.GIF_MIME:
+		return true
+	case utils.APNG_MIME:
+		mode := apngMode()
+		return mode == APNG_MODE_GIF || mode == APNG_MODE_WEBP
+	}
+
+	return false
+}
+
+// newApngMutableImage creates a new mutable image for an animated PNG, based on
+// the configured mode, and returns it
+func newApngMutableImage(img *ProcessableImage) (MutableImage, error) {
+	switch apngMode() {
+	case APNG_MODE_GIF, APNG_MODE_WEBP:
+		// Convert image to a GIF
+		g, err := decodeApng(img.Data)
+		if err != nil {
+			return nil, err
+		}
+
+		buf := &bytes.Buffer{}
+		if err = gif.EncodeAll(buf, g); err != nil {
+			return nil, err
+		}
+
+		img.Data, img.ImageType = buf.Bytes(), utils.GIF_MIME
+
+		// Create GIF mutable image, output as a WebP if needed
+		mi, err := NewGifMutableImage(img)
+		if err != nil {
+			return nil, err
+		}
+
+		if apngMode() == APNG_MODE_WEBP {
+			if err = mi.Format(utils.WEBP_MIME); err != nil {
+				return nil, err
+			}
+		}
+
+		return mi, nil
+	case APNG_MODE_PASSTHROUGH:
+		// Create pass-through mutable image
+		img.ImageType = utils.PNG_MIME
+
+		mi, err := NewPassthroughMutableImage(img)
+		if err != nil {
+			return nil, err
+		}
+
+		return mi, nil
+	}
+
+	// Create static mutable image
+	// NOTE: Only the default image of the animated PNG is processed
+	img.ImageType = utils.PNG_MIME
+
+	return NewStaticMutableImage(img)
+}
+
+// readApng reads the structure of an animated PNG, without decoding it's frames
+func readApng(data []byte) (*apngImage, error) {
+	// Verify signature
+	if !bytes.HasPrefix(data, []byte(PNG_SIGNATURE)) {
+		return nil, fmt.Errorf("Invalid animated PNG: missing signature")
+	}
+
+	var (
+		// Animated PNG to return
+		a = &apngImage{}
+		// The frame chunks are currently being read for
+		frame *apngFrame
+		// Flag indicating image data was read
+		started bool
+	)
+
+	// Loop through chunks
+	// NOTE: Each chunk is made up of it's length, type, data and a checksum
+	for offset := len(PNG_SIGNATURE); offset+8 <= len(data); {
+		length := int(binary.BigEndian.Uint32(data[offset:]))
+		if offset+12+length > len(data) {
+			return nil, fmt.Errorf("Invalid animated PNG: truncated chunk")
+		}
+
+		kind, chunk := string(data[offset+4:offset+8]), data[offset+8:offset+8+length]
+		offset += 12 + length
+
+		switch kind {
+		case "IHDR":
+			if length != 13 {
+				return nil, fmt.Errorf("Invalid animated PNG: invalid header")
+			}
+
+			a.header = chunk
+			a.width = int(binary.BigEndian.Uint32(chunk))
+			a.height = int(binary.BigEndian.Uint32(chunk[4:]))
+		case "acTL":
+			if length != 8 {
+				return nil, fmt.Errorf("Invalid animated PNG: invalid animation control")
+			}
+
+			a.plays = int(binary.BigEndian.Uint32(chunk[4:]))
+		case "fcTL":
+			if length != 26 {
+				return nil, fmt.Errorf("Invalid animated PNG: invalid frame control")
+			}
+
+			frame = newApngFrame(chunk)
+			a.frames = append(a.frames, frame)
+		case "IDAT":
+			// NOTE: The default image is only part of the animation when
+			// it follows a frame control chunk
+			started = true
+			if frame != nil {
+				frame.data = append(frame.data, chunk)
+			}
+		case "fdAT":
+			if frame == nil || length < 4 {
+				return nil, fmt.Errorf("Invalid animated PNG: frame data without frame control")
+			}
+
+			// NOTE: Frame data starts with a sequence number
+			started = true
+			frame.data = append(frame.data, chunk[4:])
+		case "IEND":
+			offset = len(data)
+		default:
+			// Keep chunks describing all frames, such as palettes
+			// NOTE: Only chunks before the image data apply to it
+			if !started {
+				a.shared = append(a.shared, data[offset-12-length:offset])
+			}
+		}
+	}
+
+	// Verify image has frames
+	if a.header == nil || len(a.frames) == 0 {
+		return nil, fmt.Errorf("Invalid animated PNG: no frames found")
+	}
+
+	// Verify dimensions before any memory is allocated for them
+	// NOTE: Dimensions are read from the image, so can't be trusted
+	if err := a.validate(); err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// validate verifies the canvas of an animated PNG is within size limits,
+// and that all of it's frames are within the canvas
+func (a *apngImage) validate() error {
+	// Verify canvas size
+	if err := validateCanvas(a.width, a.height, len(a.frames)); err != nil {
+		return fmt.Errorf("Invalid animated PNG: %s", err.Error())
+	}
+
+	// Verify frames are within the canvas
+	for n, f := range a.frames {
+		if f.width <= 0 || f.height <= 0 || f.x < 0 || f.y < 0 ||
+			int64(f.x)+int64(f.width) > int64(a.width) || int64(f.y)+int64(f.height) > int64(a.height) {
+			return fmt.Errorf("Invalid animated PNG: frame %d is outside of the canvas", n)
+		}
+	}
+
+	return nil
+}
+
+// newApngFrame creates a new `apngFrame` from the data of a frame control chunk
+func newApngFrame(chunk []byte) *apngFrame {
+	// Convert delay fraction to hundredths of a second
+	// NOTE: A denominator of 0 means hundredths of a second
+	num, den := int(binary.BigEndian.Uint16(chunk[20:])), int(binary.BigEndian.Uint16(chunk[22:]))
+	if den == 0 {
+		den = 100
+	}
+
+	return &apngFrame{
+		blend:   chunk[25],
+		delay:   (num*100 + den/2) / den,
+		dispose: chunk[24],
+		width:   int(binary.BigEndian.Uint32(chunk[4:])),
+		height:  int(binary.BigEndian.Uint32(chunk[8:])),
+		x:       int(binary.BigEndian.Uint32(chunk[12:])),
+		y:       int(binary.BigEndian.Uint32(chunk[16:])),
+	}
+}
+
+// decodeApng decodes an animated PNG into a GIF, keeping the timing and looping of it's frames
+// NOTE: Frames are rendered onto the full canvas, as GIFs can't blend frames the same way
+func decodeApng(data []byte) (*gif.GIF, error) {
+	// Read structure
+	a, err := readApng(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		// Canvas frames are drawn on
+		canvas = image.NewRGBA(image.Rect(0, 0, a.width, a.height))
+		// GIF to return
+		g = &gif.GIF{
+			Config:    image.Config{Width: a.width, Height: a.height},
+			LoopCount: loopCount(a.plays),
+		}
+	)
+
+	// Loop through frames, drawing each in turn
+	for n, frame := range a.frames {
+		// Decode frame
+		img, err := frame.decode(a)
+		if err != nil {
+			return nil, err
+		}
+
+		// Store canvas to restore after the frame if needed
+		// NOTE: The first frame is disposed of to the background instead
+		dispose := frame.dispose
+		if dispose == APNG_DISPOSE_PREVIOUS && n == 0 {
+			dispose = APNG_DISPOSE_BACKGROUND
+		}
+
+		var previous *image.RGBA
+		if dispose == APNG_DISPOSE_PREVIOUS {
+			previous = cloneRGBA(canvas)
+		}
+
+		// Draw frame
+		op, bounds := draw.Src, image.Rect(frame.x, frame.y, frame.x+frame.width, frame.y+frame.height)
+		if frame.blend == APNG_BLEND_OVER {
+			op = draw.Over
+		}
+
+		draw.Draw(canvas, bounds, img, img.Bounds().Min, op)
+
+		g.Image = append(g.Image, palettize(cloneRGBA(canvas), GIF_MAX_COLORS))
+		g.Delay = append(g.Delay, frame.delay)
+		g.Disposal = append(g.Disposal, gif.DisposalBackground)
+
+		// Dispose of frame
+		switch dispose {
+		case APNG_DISPOSE_BACKGROUND:
+			draw.Draw(canvas, bounds, image.Transparent, image.ZP, draw.Src)
+		case APNG_DISPOSE_PREVIOUS:
+			canvas = previous
+		}
+	}
+
+	return g, nil
+}
+
+// decode decodes a frame's image data, by forming a PNG containing only the frame
+func (f *apngFrame) decode(a *apngImage) (image.Image, error) {
+	buf := bytes.NewBufferString(PNG_SIGNATURE)
+
+	// Write header, with the dimensions of the frame
+	header := append([]byte{}, a.header...)
+	binary.BigEndian.PutUint32(header, uint32(f.width))
+	binary.BigEndian.PutUint32(header[4:], uint32(f.height))
+	writePngChunk(buf, "IHDR", header)
+
+	// Write shared chunks and image data
+	for _, chunk := range a.shared {
+		buf.Write(chunk)
+	}
+
+	writePngChunk(buf, "IDAT", bytes.Join(f.data, nil))
+	writePngChunk(buf, "IEND", nil)
+
+	return png.Decode(buf)
+}
+
+// writePngChunk writes a single chunk of a PNG, including it's length and checksum
+func writePngChunk(buf *bytes.Buffer, kind string, data []byte) {
+	b := make([]byte, 4)
+
+	binary.BigEndian.PutUint32(b, uint32(len(data)))
+	buf.Write(b)
+	buf.WriteString(kind)
+	buf.Write(data)
+
+	binary.BigEndian.PutUint32(b, crc32.ChecksumIEEE(append([]byte(kind), data...)))
+	buf.Write(b)
+}
@@ -0,0 +1,303 @@
+// Tests the apng.go file
+package mutableimages
+
+import (
+	// Standard lib
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"path"
+
+	// Internal
+	"github.com/marksost/img/config"
+	"github.com/marksost/img/image/utils"
+
+	// Third-party
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// mockApng returns a 4x4, two-frame animated PNG, with a solid red first frame,
+// and a 2x2 blue second frame drawn over the bottom-right corner of the canvas
+func mockApng(plays int) []byte {
+	// imageData returns the header and image data of an encoded image
+	imageData := func(img image.Image) ([]byte, []byte) {
+		buf := &bytes.Buffer{}
+		if err := png.Encode(buf, img); err != nil {
+			panic("Error encoding image. Tests cannot continue. " + err.Error())
+		}
+
+		var header, idat []byte
+
+		data := buf.Bytes()
+		for offset := len(PNG_SIGNATURE); offset < len(data); {
+			length := int(binary.BigEndian.Uint32(data[offset:]))
+			chunk := data[offset+8 : offset+8+length]
+
+			switch string(data[offset+4 : offset+8]) {
+			case "IHDR":
+				header = chunk
+			case "IDAT":
+				idat = append(idat, chunk...)
+			}
+
+			offset += 12 + length
+		}
+
+		return header, idat
+	}
+
+	// frameControl returns the data of a frame control chunk
+	frameControl := func(seq, width, height, x, y, delay int, blend byte) []byte {
+		b := make([]byte, 26)
+		binary.BigEndian.PutUint32(b, uint32(seq))
+		binary.BigEndian.PutUint32(b[4:], uint32(width))
+		binary.BigEndian.PutUint32(b[8:], uint32(height))
+		binary.BigEndian.PutUint32(b[12:], uint32(x))
+		binary.BigEndian.PutUint32(b[16:], uint32(y))
+		binary.BigEndian.PutUint16(b[20:], uint16(delay))
+		binary.BigEndian.PutUint16(b[22:], 100)
+		b[25] = blend
+
+		return b
+	}
+
+	// Form frames
+	first := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	draw.Draw(first, first.Bounds(), image.NewUniform(red), image.ZP, draw.Src)
+	second := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	draw.Draw(second, second.Bounds(), image.NewUniform(blue), image.ZP, draw.Src)
+
+	header, firstData := imageData(first)
+	_, secondData := imageData(second)
+
+	// Write chunks
+	buf := bytes.NewBufferString(PNG_SIGNATURE)
+	actl := make([]byte, 8)
+	binary.BigEndian.PutUint32(actl, 2)
+	binary.BigEndian.PutUint32(actl[4:], uint32(plays))
+
+	writePngChunk(buf, "IHDR", header)
+	writePngChunk(buf, "acTL", actl)
+	writePngChunk(buf, "fcTL", frameControl(0, 4, 4, 0, 0, 10, 0))
+	writePngChunk(buf, "IDAT", firstData)
+	writePngChunk(buf, "fcTL", frameControl(1, 2, 2, 2, 2, 20, APNG_BLEND_OVER))
+	writePngChunk(buf, "fdAT", append([]byte{0, 0, 0, 2}, secondData...))
+	writePngChunk(buf, "IEND", nil)
+
+	return buf.Bytes()
+}
+
+// setChunk replaces the data of the nth chunk of a type within a PNG,
+// updating it's checksum
+func setChunk(data []byte, kind string, n int, fn func([]byte)) []byte {
+	data = append([]byte{}, data...)
+
+	for offset := len(PNG_SIGNATURE); offset+8 <= len(data); {
+		length := int(binary.BigEndian.Uint32(data[offset:]))
+		if string(data[offset+4:offset+8]) == kind {
+			if n == 0 {
+				fn(data[offset+8 : offset+8+length])
+				binary.BigEndian.PutUint32(data[offset+8+length:], crc32.ChecksumIEEE(data[offset+4:offset+8+length]))
+				break
+			}
+
+			n--
+		}
+
+		offset += 12 + length
+	}
+
+	return data
+}
+
+var _ = Describe("apng.go", func() {
+	BeforeEach(func() {
+		// Initalize config instance
+		config.Init()
+	})
+
+	Describe("`readApng` method", func() {
+		Context("With an animated PNG", func() {
+			It("Returns the structure of the image", func() {
+				// Call method
+				a, err := readApng(mockApng(3))
+
+				// Verify return value
+				Expect(err).To(Not(HaveOccurred()))
+				Expect(a.width).To(Equal(4))
+				Expect(a.height).To(Equal(4))
+				Expect(a.plays).To(Equal(3))
+				Expect(a.frames).To(HaveLen(2))
+				Expect(a.frames[1].delay).To(Equal(20))
+				Expect(a.frames[1].x).To(Equal(2))
+				Expect(a.frames[1].blend).To(BeEquivalentTo(APNG_BLEND_OVER))
+			})
+		})
+
+		Context("With invalid data", func() {
+			It("Returns an error", func() {
+				// Verify return values
+				_, err := readApng([]byte("foo"))
+				Expect(err).To(HaveOccurred())
+
+				_, err = readApng(mockApng(0)[:40])
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("With an oversized canvas", func() {
+			It("Returns an error", func() {
+				// Set canvas dimensions
+				data := setChunk(mockApng(0), "IHDR", 0, func(chunk []byte) {
+					binary.BigEndian.PutUint32(chunk, 100000)
+					binary.BigEndian.PutUint32(chunk[4:], 100000)
+				})
+
+				// Verify return values
+				_, err := readApng(data)
+				Expect(err).To(HaveOccurred())
+
+				_, err = decodeApng(data)
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("With a frame outside of the canvas", func() {
+			It("Returns an error", func() {
+				// Move second frame past the canvas edge
+				data := setChunk(mockApng(0), "fcTL", 1, func(chunk []byte) {
+					binary.BigEndian.PutUint32(chunk[12:], 3)
+				})
+
+				// Verify return value
+				_, err := readApng(data)
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("`decodeApng` method", func() {
+		It("Returns a GIF of the image's rendered frames", func() {
+			// Call method
+			g, err := decodeApng(mockApng(3))
+
+			// Verify return value
+			Expect(err).To(Not(HaveOccurred()))
+			Expect(g.Delay).To(Equal([]int{10, 20}))
+			Expect(g.LoopCount).To(Equal(2))
+
+			frames := composeFrames(g)
+			Expect(frames).To(HaveLen(2))
+			Expect(frames[0].RGBAAt(3, 3)).To(Equal(red))
+			Expect(frames[1].RGBAAt(0, 0)).To(Equal(red))
+			Expect(frames[1].RGBAAt(3, 3)).To(Equal(blue))
+		})
+	})
+
+	Describe("`IsProcessedAsGif` method", func() {
+		It("Returns a boolean indicating if images of a type are processed as GIFs", func() {
+			// Verify return values in static mode
+			Expect(IsProcessedAsGif(utils.GIF_MIME)).To(BeTrue())
+			Expect(IsProcessedAsGif(utils.APNG_MIME)).To(BeFalse())
+			Expect(IsProcessedAsGif(utils.PNG_MIME)).To(BeFalse())
+
+			// Verify return values in other modes
+			config.GetInstance().Images.Apng.Mode = APNG_MODE_PASSTHROUGH
+			Expect(IsProcessedAsGif(utils.APNG_MIME)).To(BeFalse())
+
+			config.GetInstance().Images.Apng.Mode = APNG_MODE_GIF
+			Expect(IsProcessedAsGif(utils.APNG_MIME)).To(BeTrue())
+
+			config.GetInstance().Images.Apng.Mode = APNG_MODE_WEBP
+			Expect(IsProcessedAsGif(utils.APNG_MIME)).To(BeTrue())
+		})
+	})
+
+	Describe("`newApngMutableImage` method", func() {
+		var (
+			// Mock processable image to use throughout testing
+			pi *ProcessableImage
+		)
+
+		BeforeEach(func() {
+			// Create processable image
+			pi = &ProcessableImage{Data: mockApng(0), ImageType: utils.APNG_MIME}
+		})
+
+		Context("In static mode", func() {
+			It("Returns a static mutable image", func() {
+				// Call method
+				mi, err := newApngMutableImage(pi)
+
+				// Verify return value
+				Expect(err).To(Not(HaveOccurred()))
+				Expect(mi).To(BeAssignableToTypeOf(&StaticMutableImage{}))
+				Expect(pi.ImageType).To(Equal(utils.PNG_MIME))
+			})
+		})
+
+		Context("In GIF mode", func() {
+			BeforeEach(func() {
+				config.GetInstance().Images.Apng.Mode = APNG_MODE_GIF
+			})
+
+			It("Returns a GIF mutable image of all frames", func() {
+				// Call method
+				mi, err := newApngMutableImage(pi)
+
+				// Verify return value
+				Expect(err).To(Not(HaveOccurred()))
+				Expect(mi).To(BeAssignableToTypeOf(&GifMutableImage{}))
+				Expect(pi.ImageType).To(Equal(utils.GIF_MIME))
+				Expect(pi.Animated).To(BeTrue())
+				Expect(pi.Frames).To(Equal(2))
+				Expect(pi.LoopCount).To(Equal(0))
+
+				_, err = gif.DecodeAll(bytes.NewReader(pi.Data))
+				Expect(err).To(Not(HaveOccurred()))
+			})
+		})
+
+		Context("In WebP mode without the WebP command available", func() {
+			BeforeEach(func() {
+				config.GetInstance().Images.Apng.Mode = APNG_MODE_WEBP
+				webpCommandPath = path.Join("/", "missing", WEBP_COMMAND)
+			})
+
+			AfterEach(func() {
+				webpCommandPath = WEBP_COMMAND
+			})
+
+			It("Returns an error", func() {
+				// Call method
+				_, err := newApngMutableImage(pi)
+
+				// Verify return value
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("In pass-through mode", func() {
+			BeforeEach(func() {
+				config.GetInstance().Images.Apng.Mode = APNG_MODE_PASSTHROUGH
+			})
+
+			It("Returns a pass-through mutable image of the unchanged image", func() {
+				// Call method
+				data := pi.Data
+				mi, err := newApngMutableImage(pi)
+
+				// Verify return value
+				Expect(err).To(Not(HaveOccurred()))
+				Expect(mi).To(BeAssignableToTypeOf(&PassthroughMutableImage{}))
+				Expect(pi.Data).To(Equal(data))
+				Expect(pi.ImageType).To(Equal(utils.PNG_MIME))
+			})
+		})
+	})
+})
@@ -0,0 +1,172 @@
+// gif-command contains all functionality around running the GIF command,
+// including limiting how long and how many processes can run at once
+package mutableimages
+
+import (
+	// Standard lib
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	// Internal
+	"github.com/marksost/img/config"
+	"github.com/marksost/img/image/utils"
+	"github.com/marksost/img/metrics"
+)
+
+const (
+	// Default time a GIF command is allowed to run before it's killed
+	GIF_COMMAND_TIMEOUT = 30 * time.Second
+	// Max length of a GIF command's error output included in errors
+	GIF_COMMAND_MAX_STDERR = 512
+)
+
+var (
+	// Path of the GIF command to run
+	// NOTE: Only changed within tests
+	gifCommandPath = GIF_COMMAND
+	// Time a GIF command is allowed to run before it's killed (0 for no limit)
+	gifCommandTimeout = GIF_COMMAND_TIMEOUT
+	// Limiter used to cap the number of GIF commands run at once
+	// NOTE: A nil limiter places no limit on the number of commands
+	gifProcessLimiter *utils.Limiter
+
+	// Metrics recorded while running GIF commands
+	gifCommandMetrics = &commandMetrics{
+		duration: metrics.NewHistogram(
+			"img_gifsicle_duration_seconds",
+			"Time taken to run gifsicle subprocesses.",
+			metrics.DurationBuckets,
+		),
+		failures: metrics.NewCounter(
+			"img_gifsicle_failures_total",
+			"Number of gifsicle subprocesses that failed to run.",
+		),
+		timeouts: metrics.NewCounter(
+			"img_gifsicle_timeouts_total",
+			"Number of gifsicle subprocesses killed for running too long.",
+		),
+	}
+	_ = metrics.NewGaugeFunc(
+		"img_gifsicle_processes",
+		"Number of gifsicle subprocesses currently running, when limited.",
+		func() float64 { return float64(gifProcessLimiter.InUse()) },
+	)
+)
+
+type (
+	// Struct representing the metrics recorded while running a command
+	commandMetrics struct {
+		duration *metrics.Histogram // Time taken to run the command
+		failures *metrics.Counter   // Number of times the command failed to run
+		timeouts *metrics.Counter   // Number of times the command was killed for running too long
+	}
+	// Struct representing an error that occurred while running the GIF command
+	GifCommandError struct {
+		Args    []string // The arguments the command was run with
+		Command string   // The name of the command that was run
+		Err     error    // The error the command failed with
+		Stderr  string   // The error output of the command
+	}
+)
+
+// InitGifCommand sets up the timeout and process limit of the GIF command
+// from configuration
+func InitGifCommand() {
+	// Get configuration instance
+	c := config.GetInstance()
+
+	gifCommandTimeout = time.Duration(c.Images.Gif.Timeout) * time.Second
+	gifProcessLimiter = utils.NewLimiter(c.Images.Gif.MaxProcesses, c.Images.Concurrency.Queue)
+}
+
+// Error returns a string describing the failed command, including it's error output
+func (e *GifCommandError) Error() string {
+	str := fmt.Sprintf("%s %s failed: %s", e.Command, strings.Join(e.Args, " "), e.Err.Error())
+	if e.Stderr != "" {
+		str += ": " + e.Stderr
+	}
+
+	return str
+}
+
+// runGifCommand runs the GIF command on image data, returning the command's output
+// NOTE: The arguments of multiple operations can be passed at once to run them
+// as a single chain within one process
+func runGifCommand(data []byte, operationArgs ...[]string) ([]byte, error) {
+	// Combine arguments
+	var args []string
+	for _, a := range operationArgs {
+		args = append(args, a...)
+	}
+
+	return runCommand(GIF_COMMAND, gifCommandPath, args, data, gifCommandMetrics)
+}
+
+// runCommand runs a command with optional input, returning the command's output
+// NOTE: Commands share the timeout and process limit of the GIF command
+func runCommand(name, commandPath string, args []string, input []byte, m *commandMetrics) ([]byte, error) {
+	// Command output
+	var stdout, stderr bytes.Buffer
+
+	// Limit how long the command can run for, including waiting to be run
+	ctx, cancel := gifCommandContext()
+	defer cancel()
+
+	// Wait for a process slot
+	if err := gifProcessLimiter.AcquireContext(ctx); err != nil {
+		return nil, &GifCommandError{Args: args, Command: name, Err: err}
+	}
+
+	defer gifProcessLimiter.Release()
+
+	// Form command
+	cmd := exec.CommandContext(ctx, commandPath, args...)
+	if input != nil {
+		cmd.Stdin = bytes.NewReader(input)
+	}
+
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	// Run command, recording metrics
+	start := time.Now()
+	err := cmd.Run()
+	m.duration.Observe(time.Since(start).Seconds())
+	if err == nil {
+		return stdout.Bytes(), nil
+	}
+
+	// Report timeouts
+	m.failures.Inc()
+	if ctx.Err() == context.DeadlineExceeded {
+		m.timeouts.Inc()
+		err = fmt.Errorf("killed after running for more than %s", gifCommandTimeout)
+	}
+
+	return nil, &GifCommandError{Args: args, Command: name, Err: err, Stderr: commandOutput(&stderr)}
+}
+
+// gifCommandContext returns a context limiting how long a GIF command can run for,
+// including waiting to be run
+func gifCommandContext() (context.Context, context.CancelFunc) {
+	if gifCommandTimeout > 0 {
+		return context.WithTimeout(context.Background(), gifCommandTimeout)
+	}
+
+	return context.WithCancel(context.Background())
+}
+
+// commandOutput returns the error output of a command for use in errors,
+// truncating it if needed
+func commandOutput(stderr *bytes.Buffer) string {
+	output := strings.TrimSpace(stderr.String())
+	if len(output) > GIF_COMMAND_MAX_STDERR {
+		output = output[:GIF_COMMAND_MAX_STDERR] + "..."
+	}
+
+	return output
+}
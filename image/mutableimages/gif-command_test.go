@@ -0,0 +1,117 @@
+// Tests the gif-command.go file
+package mutableimages
+
+import (
+	// Standard lib
+	"context"
+	"strings"
+	"time"
+
+	// Internal
+	"github.com/marksost/img/config"
+	"github.com/marksost/img/image/utils"
+
+	// Third-party
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("gif-command.go", func() {
+	AfterEach(func() {
+		// Reset command settings
+		gifCommandPath = GIF_COMMAND
+		gifCommandTimeout = GIF_COMMAND_TIMEOUT
+		gifProcessLimiter = nil
+	})
+
+	Describe("`InitGifCommand` method", func() {
+		BeforeEach(func() {
+			// Initalize config instance
+			config.Init()
+
+			// Set command settings
+			config.GetInstance().Images.Gif.MaxProcesses = 2
+			config.GetInstance().Images.Gif.Timeout = 5
+		})
+
+		It("Sets up the command's timeout and process limit", func() {
+			// Call method
+			InitGifCommand()
+
+			// Verify settings
+			Expect(gifCommandTimeout).To(Equal(5 * time.Second))
+			Expect(gifProcessLimiter).To(Not(BeNil()))
+		})
+	})
+
+	Describe("`runGifCommand` method", func() {
+		BeforeEach(func() {
+			// Use a shell in place of the GIF command
+			gifCommandPath = "sh"
+		})
+
+		Context("With a command that succeeds", func() {
+			It("Returns the command's output, combining the arguments of each operation", func() {
+				// Call method
+				// NOTE: The input is read from stdin and written back out
+				output, err := runGifCommand([]byte("foo"), []string{"-c"}, []string{"cat; echo bar"})
+
+				// Verify return values
+				Expect(err).To(Not(HaveOccurred()))
+				Expect(string(output)).To(Equal("foobar\n"))
+			})
+		})
+
+		Context("With a command that fails", func() {
+			It("Returns an error including the command's error output", func() {
+				// Call method
+				_, err := runGifCommand(nil, []string{"-c", "echo 'bad input' >&2; exit 1"})
+
+				// Verify return value
+				Expect(err).To(BeAssignableToTypeOf(&GifCommandError{}))
+				Expect(err.(*GifCommandError).Stderr).To(Equal("bad input"))
+				Expect(err.Error()).To(HavePrefix(GIF_COMMAND + " -c"))
+				Expect(err.Error()).To(HaveSuffix(": bad input"))
+			})
+		})
+
+		Context("With a command that runs for too long", func() {
+			BeforeEach(func() {
+				// Set timeout
+				gifCommandTimeout = 50 * time.Millisecond
+			})
+
+			It("Kills the command and returns an error", func() {
+				// Call method
+				// NOTE: Exec'd so the killed process is the one holding the output open
+				start := time.Now()
+				_, err := runGifCommand(nil, []string{"-c", "exec sleep 5"})
+
+				// Verify return value
+				Expect(err).To(HaveOccurred())
+				Expect(strings.Contains(err.Error(), "killed after running")).To(BeTrue())
+				Expect(time.Since(start)).To(BeNumerically("<", 5*time.Second))
+			})
+		})
+
+		Context("With no free process slots", func() {
+			BeforeEach(func() {
+				// Take only process slot
+				gifProcessLimiter = utils.NewLimiter(1, 1)
+				gifProcessLimiter.Acquire()
+
+				// Set timeout
+				gifCommandTimeout = 50 * time.Millisecond
+			})
+
+			It("Waits for a slot until timing out", func() {
+				// Call method
+				_, err := runGifCommand(nil, []string{"-c", "true"})
+
+				// Verify return value
+				Expect(err).To(HaveOccurred())
+				Expect(err.(*GifCommandError).Err).To(Equal(context.DeadlineExceeded))
+			})
+		})
+	})
+})
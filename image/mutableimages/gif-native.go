@@ -0,0 +1,601 @@
+// gif-native contains a pure Go implementation of the processing actions supported
+// on GIF images, used in place of the GIF command when it isn't available
+package mutableimages
+
+import (
+	// Standard lib
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"os/exec"
+	"sort"
+	"sync"
+
+	// Internal
+	"github.com/marksost/img/config"
+	"github.com/marksost/img/values"
+)
+
+const (
+	// Names of the engines GIFs can be processed with
+	GIF_ENGINE_AUTO     = "auto"
+	GIF_ENGINE_GIFSICLE = "gifsicle"
+	GIF_ENGINE_NATIVE   = "native"
+
+	// Alpha value below which pixels are treated as transparent
+	// NOTE: GIF pixels can only be fully transparent or fully opaque
+	GIF_ALPHA_THRESHOLD = 0x80
+	// The max number of colors in a GIF palette
+	GIF_MAX_COLORS = 256
+	// The min number of colors in a GIF palette
+	GIF_MIN_COLORS = 2
+)
+
+var (
+	// Flag indicating the GIF command was found on the host system
+	gifCommandFound bool
+	// Used to look up the GIF command only once
+	gifCommandLookup sync.Once
+)
+
+type (
+	// Struct used to sort colors by a single RGB channel (0, 1 or 2),
+	// or by all channels (-1)
+	byChannel struct {
+		channel int             // The channel to sort by
+		colors  []weightedColor // The colors to sort
+	}
+	// Struct representing a color and the number of pixels using it
+	weightedColor struct {
+		color color.RGBA // The color
+		count int        // Number of pixels using the color
+	}
+)
+
+// useNativeGifEngine returns a boolean indicating if GIFs should be processed
+// with the native engine, based on configuration and the availability of the GIF command
+func useNativeGifEngine() bool {
+	// Get configured engine
+	engine := GIF_ENGINE_AUTO
+	if c := config.GetInstance(); c != nil {
+		engine = c.Images.Gif.Engine
+	}
+
+	switch engine {
+	case GIF_ENGINE_GIFSICLE:
+		return false
+	case GIF_ENGINE_NATIVE:
+		return true
+	}
+
+	// Fall back to the native engine when the GIF command isn't installed
+	gifCommandLookup.Do(func() {
+		_, err := exec.LookPath(GIF_COMMAND)
+		gifCommandFound = err == nil
+	})
+
+	return !gifCommandFound
+}
+
+// Len returns the number of colors being sorted
+func (s byChannel) Len() int {
+	return len(s.colors)
+}
+
+// Less returns a boolean indicating if one color should be sorted before another
+func (s byChannel) Less(a, b int) bool {
+	ca, cb := s.colors[a].color, s.colors[b].color
+	if s.channel < 0 {
+		return uint32(ca.R)<<16|uint32(ca.G)<<8|uint32(ca.B) < uint32(cb.R)<<16|uint32(cb.G)<<8|uint32(cb.B)
+	}
+
+	return channelValue(ca, s.channel) < channelValue(cb, s.channel)
+}
+
+// Swap swaps two colors being sorted
+func (s byChannel) Swap(a, b int) {
+	s.colors[a], s.colors[b] = s.colors[b], s.colors[a]
+}
+
+/* Begin native operation methods */
+
+// nativeCrop crops every frame of the image to a rectangle of the canvas
+func (i *GifMutableImage) nativeCrop(vals *values.CropValues) error {
+	// Form crop rectangle, verifying it's within the canvas
+	frames := composeFrames(i.decodedData)
+	rect := image.Rect(int(vals.X), int(vals.Y), int(vals.X+vals.Width), int(vals.Y+vals.Height))
+	if rect.Empty() || !rect.In(frames[0].Bounds()) {
+		return fmt.Errorf("Crop area %v is outside of the image", rect)
+	}
+
+	// Crop frames
+	for n, frame := range frames {
+		frames[n] = frame.SubImage(rect).(*image.RGBA)
+	}
+
+	return i.setFrames(frames)
+}
+
+// nativeQuality reduces the number of colors of the image, sharing a single palette
+// between all frames
+// NOTE: Frames are remapped in place, keeping their offsets and disposal methods
+func (i *GifMutableImage) nativeQuality(colors int) error {
+	// Skip images whose frames are already within the number of colors
+	// NOTE: Keeps each frame's own palette, rather than merging them into a shared one
+	if colors >= GIF_MAX_COLORS && !exceedsColors(i.decodedData, colors) {
+		return nil
+	}
+
+	var (
+		// Map of colors used by the image to the number of pixels using them
+		counts = make(map[color.RGBA]int)
+		// Flag indicating the image contains transparent pixels
+		transparent bool
+	)
+
+	// Count pixels using each color, across all frames
+	for _, frame := range i.decodedData.Image {
+		usage := make([]int, len(frame.Palette))
+		for _, idx := range frame.Pix {
+			if int(idx) < len(usage) {
+				usage[idx]++
+			}
+		}
+
+		for idx, count := range usage {
+			if count == 0 {
+				continue
+			}
+
+			c, ok := opaqueColor(frame.Palette[idx])
+			if !ok {
+				transparent = true
+				continue
+			}
+
+			counts[c] += count
+		}
+	}
+
+	// Form shared palette, reserving a slot for transparent pixels if needed
+	palette := quantize(counts, colors-boolToInt(transparent))
+	if transparent || len(palette) == 0 {
+		palette = append(palette, color.RGBA{})
+	}
+
+	// Remap frames to the shared palette
+	for _, frame := range i.decodedData.Image {
+		mapping := make([]uint8, len(frame.Palette))
+		for idx, c := range frame.Palette {
+			if oc, ok := opaqueColor(c); ok {
+				mapping[idx] = uint8(palette.Index(oc))
+			} else {
+				mapping[idx] = uint8(len(palette) - 1)
+			}
+		}
+
+		for p, idx := range frame.Pix {
+			if int(idx) < len(mapping) {
+				frame.Pix[p] = mapping[idx]
+			}
+		}
+
+		frame.Palette = palette
+	}
+
+	// Use shared palette as the global palette, so it's only stored once
+	i.decodedData.Config.ColorModel = palette
+	i.decodedData.BackgroundIndex = 0
+
+	return i.encode()
+}
+
+// nativeResize resizes every frame of the image
+// NOTE: Only downsizing is supported, which is done by averaging the pixels
+// each resized pixel covers
+func (i *GifMutableImage) nativeResize(vals *values.DimensionValues) error {
+	// Verify dimensions
+	if vals.Width <= 0 || vals.Height <= 0 {
+		return fmt.Errorf("Invalid resize dimensions: %dx%d", vals.Width, vals.Height)
+	}
+
+	// Resize frames
+	frames := composeFrames(i.decodedData)
+	for n, frame := range frames {
+		frames[n] = resizeRGBA(frame, int(vals.Width), int(vals.Height))
+	}
+
+	return i.setFrames(frames)
+}
+
+/* End native operation methods */
+
+/* Begin native utility methods */
+
+// setFrames replaces the frames of the image with full-canvas frames,
+// keeping the timing and looping of the image
+func (i *GifMutableImage) setFrames(frames []*image.RGBA) error {
+	// Convert frames to paletted images
+	images := make([]*image.Paletted, len(frames))
+	disposal := make([]byte, len(frames))
+	for n, frame := range frames {
+		images[n] = palettize(frame, GIF_MAX_COLORS)
+
+		// NOTE: Frames cover the whole canvas, so clearing each one before the next
+		// keeps transparent pixels from showing previous frames
+		disposal[n] = gif.DisposalBackground
+	}
+
+	// Set frames and canvas
+	bounds := images[0].Bounds()
+	i.decodedData.Image = images
+	i.decodedData.Disposal = disposal
+	i.decodedData.BackgroundIndex = 0
+	i.decodedData.Config = image.Config{Width: bounds.Dx(), Height: bounds.Dy()}
+
+	return i.encode()
+}
+
+// encode encodes the decoded data of the image, replacing the image's data
+func (i *GifMutableImage) encode() error {
+	// Encode image
+	buf := &bytes.Buffer{}
+	if err := gif.EncodeAll(buf, i.decodedData); err != nil {
+		return err
+	}
+
+	// Reset image data and dimensions
+	i.img.Data = buf.Bytes()
+	i.SetDimensions()
+
+	return nil
+}
+
+// composeFrame renders a single frame of a GIF onto the full canvas, drawn on top of the frames
+// before it, without keeping a copy of any other frame
+func composeFrame(g *gif.GIF, index int) *image.RGBA {
+	var frame *image.RGBA
+
+	// Draw frames, stopping once the requested frame is drawn
+	drawFrames(g, func(n int, canvas *image.RGBA) bool {
+		if n < index {
+			return true
+		}
+
+		frame = cloneRGBA(canvas)
+		return false
+	})
+
+	return frame
+}
+
+// composeFrames renders each frame of a GIF onto the full canvas, applying the disposal
+// method of each frame before drawing the next, so that frames no longer depend on each other
+func composeFrames(g *gif.GIF) []*image.RGBA {
+	frames := make([]*image.RGBA, 0, len(g.Image))
+
+	// Draw frames, keeping a copy of each
+	drawFrames(g, func(n int, canvas *image.RGBA) bool {
+		frames = append(frames, cloneRGBA(canvas))
+		return true
+	})
+
+	return frames
+}
+
+// drawFrames draws each frame of a GIF in turn onto a single canvas, applying the disposal
+// method of each frame before drawing the next. A function is called with the canvas
+// after each frame is drawn, and drawing stops when it returns false
+// NOTE: The canvas is reused between frames, so must be copied to be kept
+func drawFrames(g *gif.GIF, fn func(int, *image.RGBA) bool) {
+	// Get canvas bounds, falling back to the bounds of all frames if not set
+	bounds := image.Rect(0, 0, g.Config.Width, g.Config.Height)
+	if bounds.Empty() {
+		for _, frame := range g.Image {
+			bounds = bounds.Union(frame.Bounds())
+		}
+	}
+
+	// Canvas frames are drawn on
+	canvas := image.NewRGBA(bounds)
+
+	// Loop through frames, drawing each in turn
+	for n, frame := range g.Image {
+		// Get frame's disposal method
+		var disposal byte
+		if n < len(g.Disposal) {
+			disposal = g.Disposal[n]
+		}
+
+		// Store canvas to restore after the frame if needed
+		var previous *image.RGBA
+		if disposal == gif.DisposalPrevious {
+			previous = cloneRGBA(canvas)
+		}
+
+		// Draw frame
+		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+		if !fn(n, canvas) {
+			return
+		}
+
+		// Dispose of frame
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, frame.Bounds(), image.Transparent, image.ZP, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = previous
+		}
+	}
+}
+
+// exceedsColors returns a boolean indicating if any frame of a GIF
+// has a palette with more than a number of colors
+func exceedsColors(g *gif.GIF, colors int) bool {
+	for _, frame := range g.Image {
+		if len(frame.Palette) > colors {
+			return true
+		}
+	}
+
+	return false
+}
+
+// resizeRGBA resizes an image by averaging the pixels each resized pixel covers
+func resizeRGBA(src *image.RGBA, width, height int) *image.RGBA {
+	var (
+		// Resized image
+		dst = image.NewRGBA(image.Rect(0, 0, width, height))
+		// Bounds of the source image
+		b = src.Bounds()
+	)
+
+	for y := 0; y < height; y++ {
+		// Get source rows covered by the pixel
+		sy0, sy1 := b.Min.Y+y*b.Dy()/height, b.Min.Y+(y+1)*b.Dy()/height
+		if sy1 <= sy0 {
+			sy1 = sy0 + 1
+		}
+
+		for x := 0; x < width; x++ {
+			// Get source columns covered by the pixel
+			sx0, sx1 := b.Min.X+x*b.Dx()/width, b.Min.X+(x+1)*b.Dx()/width
+			if sx1 <= sx0 {
+				sx1 = sx0 + 1
+			}
+
+			// Average covered pixels
+			// NOTE: Pixels are alpha-premultiplied, so transparent pixels don't tint the result
+			var r, g, bl, a, count int
+			for sy := sy0; sy < sy1; sy++ {
+				for sx := sx0; sx < sx1; sx++ {
+					c := src.RGBAAt(sx, sy)
+					r, g, bl, a = r+int(c.R), g+int(c.G), bl+int(c.B), a+int(c.A)
+					count++
+				}
+			}
+
+			dst.SetRGBA(x, y, color.RGBA{
+				R: uint8(r / count),
+				G: uint8(g / count),
+				B: uint8(bl / count),
+				A: uint8(a / count),
+			})
+		}
+	}
+
+	return dst
+}
+
+// palettize converts an image to a paletted image with at most a given number of colors,
+// reserving the last color of the palette for transparent pixels if needed
+func palettize(img *image.RGBA, colors int) *image.Paletted {
+	var (
+		// Bounds of the image
+		b = img.Bounds()
+		// Map of colors used by the image to the number of pixels using them
+		counts = make(map[color.RGBA]int)
+		// Flag indicating the image contains transparent pixels
+		transparent bool
+	)
+
+	// Count pixels using each color
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if c, ok := opaqueColor(img.RGBAAt(x, y)); ok {
+				counts[c]++
+			} else {
+				transparent = true
+			}
+		}
+	}
+
+	// Form palette
+	palette := quantize(counts, colors-boolToInt(transparent))
+	if transparent || len(palette) == 0 {
+		palette = append(palette, color.RGBA{})
+	}
+
+	// Map pixels to the palette, caching the index of each color
+	dst := image.NewPaletted(image.Rect(0, 0, b.Dx(), b.Dy()), palette)
+	indexes := make(map[color.RGBA]uint8)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c, ok := opaqueColor(img.RGBAAt(x, y))
+			if !ok {
+				dst.SetColorIndex(x-b.Min.X, y-b.Min.Y, uint8(len(palette)-1))
+				continue
+			}
+
+			idx, found := indexes[c]
+			if !found {
+				idx = uint8(palette.Index(c))
+				indexes[c] = idx
+			}
+
+			dst.SetColorIndex(x-b.Min.X, y-b.Min.Y, idx)
+		}
+	}
+
+	return dst
+}
+
+// quantize forms a palette of at most a given number of colors representing a set of colors,
+// using the median cut algorithm when there are too many colors to use them all
+func quantize(counts map[color.RGBA]int, colors int) color.Palette {
+	// Form sorted slice of colors
+	// NOTE: Sorted so that palettes are the same for the same image
+	weighted := make([]weightedColor, 0, len(counts))
+	for c, count := range counts {
+		weighted = append(weighted, weightedColor{color: c, count: count})
+	}
+
+	sort.Sort(byChannel{colors: weighted, channel: -1})
+
+	// Use all colors when they fit
+	if colors < 1 {
+		colors = 1
+	}
+
+	if len(weighted) <= colors {
+		palette := make(color.Palette, len(weighted))
+		for n, wc := range weighted {
+			palette[n] = wc.color
+		}
+
+		return palette
+	}
+
+	// Split boxes of colors until there are enough
+	boxes := [][]weightedColor{weighted}
+	for len(boxes) < colors {
+		// Find the box with the widest channel range
+		widest, channel, widestRange := -1, 0, 0
+		for n, box := range boxes {
+			if len(box) < 2 {
+				continue
+			}
+
+			if c, r := widestChannel(box); r > widestRange {
+				widest, channel, widestRange = n, c, r
+			}
+		}
+
+		// Stop when no box can be split
+		if widest < 0 {
+			break
+		}
+
+		// Split box at the median pixel of the widest channel
+		box := boxes[widest]
+		sort.Stable(byChannel{colors: box, channel: channel})
+
+		total := 0
+		for _, wc := range box {
+			total += wc.count
+		}
+
+		split, sum := 1, 0
+		for n, wc := range box[:len(box)-1] {
+			sum += wc.count
+			split = n + 1
+			if sum*2 >= total {
+				break
+			}
+		}
+
+		boxes[widest] = box[:split]
+		boxes = append(boxes, box[split:])
+	}
+
+	// Use the average color of each box
+	palette := make(color.Palette, len(boxes))
+	for n, box := range boxes {
+		var r, g, b, total int
+		for _, wc := range box {
+			r, g, b = r+int(wc.color.R)*wc.count, g+int(wc.color.G)*wc.count, b+int(wc.color.B)*wc.count
+			total += wc.count
+		}
+
+		palette[n] = color.RGBA{R: uint8(r / total), G: uint8(g / total), B: uint8(b / total), A: 0xff}
+	}
+
+	return palette
+}
+
+// widestChannel returns the RGB channel (0, 1 or 2) with the widest range
+// of values within a set of colors, and the range
+func widestChannel(box []weightedColor) (int, int) {
+	widest, widestRange := 0, -1
+	for channel := 0; channel < 3; channel++ {
+		min, max := 0xff, 0
+		for _, wc := range box {
+			v := channelValue(wc.color, channel)
+			if v < min {
+				min = v
+			}
+			if v > max {
+				max = v
+			}
+		}
+
+		if max-min > widestRange {
+			widest, widestRange = channel, max-min
+		}
+	}
+
+	return widest, widestRange
+}
+
+// channelValue returns the value of a single RGB channel (0, 1 or 2) of a color
+func channelValue(c color.RGBA, channel int) int {
+	switch channel {
+	case 0:
+		return int(c.R)
+	case 1:
+		return int(c.G)
+	}
+
+	return int(c.B)
+}
+
+// opaqueColor converts a color to an opaque, non-premultiplied color, returning false
+// if the color should be treated as transparent instead
+func opaqueColor(c color.Color) (color.RGBA, bool) {
+	rgba := color.RGBAModel.Convert(c).(color.RGBA)
+	if rgba.A < GIF_ALPHA_THRESHOLD {
+		return color.RGBA{}, false
+	}
+
+	if rgba.A < 0xff {
+		a := int(rgba.A)
+		rgba.R = uint8(int(rgba.R) * 0xff / a)
+		rgba.G = uint8(int(rgba.G) * 0xff / a)
+		rgba.B = uint8(int(rgba.B) * 0xff / a)
+		rgba.A = 0xff
+	}
+
+	return rgba, true
+}
+
+// cloneRGBA returns a copy of an image
+func cloneRGBA(img *image.RGBA) *image.RGBA {
+	c := image.NewRGBA(img.Bounds())
+	copy(c.Pix, img.Pix)
+
+	return c
+}
+
+// boolToInt returns 1 for true, and 0 for false
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+
+	return 0
+}
+
+/* End native utility methods */
@@ -0,0 +1,299 @@
+// Tests the gif-native.go file
+package mutableimages
+
+import (
+	// Standard lib
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"os/exec"
+
+	// Internal
+	"github.com/marksost/img/config"
+	"github.com/marksost/img/image/utils"
+	"github.com/marksost/img/values"
+
+	// Third-party
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var (
+	// Colors used by mock GIFs
+	blue  = color.RGBA{B: 0xff, A: 0xff}
+	green = color.RGBA{G: 0xff, A: 0xff}
+	red   = color.RGBA{R: 0xff, A: 0xff}
+)
+
+// mockAnimatedGif returns a 4x4, two-frame GIF, with a solid red first frame,
+// and a 2x2 blue second frame offset to the bottom-right corner of the canvas
+func mockAnimatedGif(disposal byte) *gif.GIF {
+	palette := color.Palette{red, blue, color.RGBA{}}
+
+	// Form frames
+	first := image.NewPaletted(image.Rect(0, 0, 4, 4), palette)
+	second := image.NewPaletted(image.Rect(2, 2, 4, 4), palette)
+	for n := range second.Pix {
+		second.Pix[n] = 1
+	}
+
+	return &gif.GIF{
+		Config:    image.Config{Width: 4, Height: 4, ColorModel: palette},
+		Delay:     []int{10, 20},
+		Disposal:  []byte{disposal, gif.DisposalNone},
+		Image:     []*image.Paletted{first, second},
+		LoopCount: 3,
+	}
+}
+
+// mockGifMutableImage returns a GIF mutable image for a decoded GIF
+func mockGifMutableImage(g *gif.GIF) *GifMutableImage {
+	buf := &bytes.Buffer{}
+	if err := gif.EncodeAll(buf, g); err != nil {
+		panic("Error encoding image. Tests cannot continue. " + err.Error())
+	}
+
+	mi, err := NewGifMutableImage(&ProcessableImage{Data: buf.Bytes(), ImageType: utils.GIF_MIME})
+	if err != nil {
+		panic("Error creating GIF mutable image. Tests cannot continue. " + err.Error())
+	}
+
+	return mi
+}
+
+// decodeFrames decodes the data of a GIF mutable image, returning it and it's rendered frames
+func decodeFrames(mi *GifMutableImage) (*gif.GIF, []*image.RGBA) {
+	g, err := gif.DecodeAll(bytes.NewReader(mi.Img().Data))
+	Expect(err).To(Not(HaveOccurred()))
+
+	return g, composeFrames(g)
+}
+
+var _ = Describe("gif-native.go", func() {
+	BeforeEach(func() {
+		// Initalize config instance
+		config.Init()
+	})
+
+	Describe("`useNativeGifEngine` method", func() {
+		It("Returns a boolean based on the configured engine", func() {
+			// Verify return values
+			config.GetInstance().Images.Gif.Engine = GIF_ENGINE_NATIVE
+			Expect(useNativeGifEngine()).To(BeTrue())
+
+			config.GetInstance().Images.Gif.Engine = GIF_ENGINE_GIFSICLE
+			Expect(useNativeGifEngine()).To(BeFalse())
+		})
+
+		It("Falls back to the native engine when the GIF command is unavailable", func() {
+			// Check for command on the host system
+			_, lookupErr := exec.LookPath(GIF_COMMAND)
+
+			// Verify return value
+			config.GetInstance().Images.Gif.Engine = GIF_ENGINE_AUTO
+			Expect(useNativeGifEngine()).To(Equal(lookupErr != nil))
+		})
+	})
+
+	Describe("Native operation methods", func() {
+		var (
+			// Mock GIF mutable image to test
+			mi *GifMutableImage
+		)
+
+		BeforeEach(func() {
+			// Use native engine
+			config.GetInstance().Images.Gif.Engine = GIF_ENGINE_NATIVE
+
+			// Create mock image
+			mi = mockGifMutableImage(mockAnimatedGif(gif.DisposalNone))
+		})
+
+		Describe("`Crop` method", func() {
+			Context("With an area within the image", func() {
+				It("Crops every frame, including offset frames", func() {
+					// Call method
+					Expect(mi.Crop(&values.CropValues{X: 1, Y: 1, Width: 2, Height: 2})).To(Succeed())
+
+					// Verify dimensions
+					Expect(mi.GetWidth()).To(Equal(int64(2)))
+					Expect(mi.GetHeight()).To(Equal(int64(2)))
+
+					// Verify frames
+					g, frames := decodeFrames(mi)
+					Expect(frames).To(HaveLen(2))
+					Expect(frames[0].RGBAAt(1, 1)).To(Equal(red))
+					Expect(frames[1].RGBAAt(0, 0)).To(Equal(red))
+					Expect(frames[1].RGBAAt(1, 1)).To(Equal(blue))
+
+					// Verify timing and looping were kept
+					Expect(g.Delay).To(Equal([]int{10, 20}))
+					Expect(g.LoopCount).To(Equal(3))
+				})
+			})
+
+			Context("With an area outside of the image", func() {
+				It("Returns an error", func() {
+					// Verify return value
+					Expect(mi.Crop(&values.CropValues{X: 3, Y: 3, Width: 2, Height: 2})).To(HaveOccurred())
+				})
+			})
+		})
+
+		Describe("`Resize` method", func() {
+			It("Resizes every frame, including offset frames", func() {
+				// Call method
+				Expect(mi.Resize(&values.DimensionValues{Width: 2, Height: 2})).To(Succeed())
+
+				// Verify dimensions
+				Expect(mi.GetWidth()).To(Equal(int64(2)))
+				Expect(mi.GetHeight()).To(Equal(int64(2)))
+
+				// Verify frames
+				_, frames := decodeFrames(mi)
+				Expect(frames).To(HaveLen(2))
+				Expect(frames[0].RGBAAt(1, 1)).To(Equal(red))
+				Expect(frames[1].RGBAAt(0, 0)).To(Equal(red))
+				Expect(frames[1].RGBAAt(1, 1)).To(Equal(blue))
+			})
+		})
+
+		Describe("`Quality` method", func() {
+			BeforeEach(func() {
+				// Create mock image with three colors and transparency
+				g := mockAnimatedGif(gif.DisposalNone)
+				g.Image[0].Palette = color.Palette{red, green, blue, color.RGBA{}}
+				g.Config.ColorModel = g.Image[0].Palette
+				g.Image[0].Pix[0], g.Image[0].Pix[1], g.Image[0].Pix[2] = 1, 2, 3
+
+				mi = mockGifMutableImage(g)
+			})
+
+			It("Reduces the number of colors, keeping transparency and frame offsets", func() {
+				// Call method
+				// NOTE: A quality of 1 converts to the min number of colors
+				Expect(mi.Quality(1)).To(Succeed())
+
+				// Verify palette
+				g, _ := decodeFrames(mi)
+				Expect(g.Image).To(HaveLen(2))
+				Expect(g.Image[0].Palette).To(HaveLen(2))
+				Expect(g.Image[0].Palette[1]).To(Equal(color.RGBA{}))
+
+				// Verify transparent pixel and frame offset were kept
+				Expect(g.Image[0].ColorIndexAt(2, 0)).To(Equal(uint8(1)))
+				Expect(g.Image[1].Bounds()).To(Equal(image.Rect(2, 2, 4, 4)))
+			})
+
+			Context("With a quality that allows all colors", func() {
+				BeforeEach(func() {
+					// Give each frame it's own palette
+					g := mockAnimatedGif(gif.DisposalNone)
+					g.Image[1].Palette = color.Palette{green, blue}
+
+					mi = mockGifMutableImage(g)
+				})
+
+				It("Leaves the image unchanged", func() {
+					// Store original data
+					data := mi.Img().Data
+
+					// Call method
+					Expect(mi.Quality(100)).To(Succeed())
+
+					// Verify data and frame palettes
+					Expect(mi.Img().Data).To(Equal(data))
+
+					g, _ := decodeFrames(mi)
+					Expect(g.Image[0].Palette[:3]).To(Equal(color.Palette{red, blue, color.RGBA{}}))
+					Expect(g.Image[1].Palette).To(Equal(color.Palette{green, blue}))
+				})
+			})
+		})
+	})
+
+	Describe("`composeFrame` method", func() {
+		It("Renders the same frame as rendering all frames", func() {
+			// Loop through disposal methods
+			for _, disposal := range []byte{gif.DisposalNone, gif.DisposalBackground, gif.DisposalPrevious} {
+				g := mockAnimatedGif(disposal)
+				frames := composeFrames(g)
+
+				// Verify each frame
+				for n := range frames {
+					Expect(composeFrame(g, n)).To(Equal(frames[n]))
+				}
+			}
+		})
+	})
+
+	Describe("`composeFrames` method", func() {
+		Context("With frames that aren't disposed of", func() {
+			It("Draws each frame over the previous frames", func() {
+				// Call method
+				frames := composeFrames(mockAnimatedGif(gif.DisposalNone))
+
+				// Verify frames
+				Expect(frames[1].Bounds()).To(Equal(image.Rect(0, 0, 4, 4)))
+				Expect(frames[1].RGBAAt(0, 0)).To(Equal(red))
+				Expect(frames[1].RGBAAt(3, 3)).To(Equal(blue))
+			})
+		})
+
+		Context("With frames disposed of to the background", func() {
+			It("Clears each frame before drawing the next", func() {
+				// Call method
+				frames := composeFrames(mockAnimatedGif(gif.DisposalBackground))
+
+				// Verify frames
+				Expect(frames[0].RGBAAt(0, 0)).To(Equal(red))
+				Expect(frames[1].RGBAAt(0, 0)).To(Equal(color.RGBA{}))
+				Expect(frames[1].RGBAAt(3, 3)).To(Equal(blue))
+			})
+		})
+
+		Context("With frames disposed of to the previous frame", func() {
+			It("Restores the canvas before drawing the next frame", func() {
+				// Call method
+				frames := composeFrames(mockAnimatedGif(gif.DisposalPrevious))
+
+				// Verify frames
+				// NOTE: The canvas was empty before the first frame
+				Expect(frames[0].RGBAAt(0, 0)).To(Equal(red))
+				Expect(frames[1].RGBAAt(0, 0)).To(Equal(color.RGBA{}))
+				Expect(frames[1].RGBAAt(3, 3)).To(Equal(blue))
+			})
+		})
+	})
+
+	Describe("`quantize` method", func() {
+		Context("With fewer colors than allowed", func() {
+			It("Returns all colors", func() {
+				// Call method
+				palette := quantize(map[color.RGBA]int{red: 1, blue: 2}, 4)
+
+				// Verify return value
+				Expect(palette).To(ConsistOf(color.Color(red), color.Color(blue)))
+			})
+		})
+
+		Context("With more colors than allowed", func() {
+			It("Returns averaged colors", func() {
+				// Call method
+				palette := quantize(map[color.RGBA]int{
+					red:                          1,
+					color.RGBA{R: 0xf0, A: 0xff}: 1,
+					blue:                         1,
+					color.RGBA{B: 0xf0, A: 0xff}: 1,
+				}, 2)
+
+				// Verify return value
+				Expect(palette).To(ConsistOf(
+					color.Color(color.RGBA{R: 0xf7, A: 0xff}),
+					color.Color(color.RGBA{B: 0xf7, A: 0xff}),
+				))
+			})
+		})
+	})
+})
@@ -0,0 +1,88 @@
+// gif-quality contains all functionality around converting a requested quality
+// into the settings used to compress a GIF
+package mutableimages
+
+import (
+	// Standard lib
+	"fmt"
+	"math"
+)
+
+const (
+	// The quality at or above which GIFs keep a full palette
+	GIF_FULL_PALETTE_QUALITY = 50
+	// The quality at or above which GIFs are optimized for speed rather than size
+	GIF_FAST_OPTIMIZATION_QUALITY = 90
+	// The amount of lossy compression added for each point of quality below 100
+	// NOTE: The GIF command accepts lossy values up to 200
+	GIF_LOSSY_FACTOR = 2
+
+	// The command argument used to apply lossy compression to a GIF
+	GIF_LOSSY_COMMAND = "--lossy=%d"
+	// The command argument used to optimize a GIF
+	GIF_OPTIMIZE_COMMAND = "-O%d"
+)
+
+type (
+	// Struct representing the settings used to compress a GIF at a quality
+	gifQuality struct {
+		colors       int // The number of colors in the palette
+		lossy        int // The amount of lossy compression (0 for none)
+		optimization int // The optimization level, from 1 (fastest) to 3 (smallest)
+	}
+)
+
+// newGifQuality converts a quality between 1 and 100 into GIF compression settings,
+// following a curve where each setting only ever lowers the file size as quality drops:
+//
+//	Quality | Colors               | Lossy           | Optimization
+//	90-100  | 256                  | 0-20            | 1
+//	50-89   | 256                  | 22-100          | 2
+//	1-49    | 2^(1+7*quality/50)   | 102-198         | 3
+//
+// NOTE: Palette size only drops at low qualities, where banding is expected,
+// as lossy compression removes far more data with fewer visible artifacts
+func newGifQuality(val int64) gifQuality {
+	// Clamp quality to range
+	if val < 1 {
+		val = 1
+	} else if val > 100 {
+		val = 100
+	}
+
+	q := gifQuality{
+		colors:       GIF_MAX_COLORS,
+		lossy:        int(100-val) * GIF_LOSSY_FACTOR,
+		optimization: 1,
+	}
+
+	// Increase optimization and reduce palette as quality drops
+	if val < GIF_FAST_OPTIMIZATION_QUALITY {
+		q.optimization = 2
+	}
+
+	if val < GIF_FULL_PALETTE_QUALITY {
+		q.optimization = 3
+		q.colors = int(math.Pow(2, 1+7*float64(val)/GIF_FULL_PALETTE_QUALITY))
+		if q.colors < GIF_MIN_COLORS {
+			q.colors = GIF_MIN_COLORS
+		}
+	}
+
+	return q
+}
+
+// args returns the GIF command arguments used to compress a GIF with these settings
+func (q gifQuality) args() []string {
+	args := []string{fmt.Sprintf(GIF_OPTIMIZE_COMMAND, q.optimization)}
+
+	if q.lossy > 0 {
+		args = append(args, fmt.Sprintf(GIF_LOSSY_COMMAND, q.lossy))
+	}
+
+	if q.colors < GIF_MAX_COLORS {
+		args = append(args, fmt.Sprintf(GIF_QUALITY_COMMAND, q.colors))
+	}
+
+	return args
+}
@@ -0,0 +1,135 @@
+// Tests the gif-quality.go file
+package mutableimages
+
+import (
+	// Standard lib
+	"bytes"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/gif"
+	"os/exec"
+
+	// Internal
+	"github.com/marksost/img/config"
+	"github.com/marksost/img/image/utils"
+
+	// Third-party
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// mockColorfulGif returns an encoded 32x32, two-frame GIF using a full palette
+func mockColorfulGif() []byte {
+	g := &gif.GIF{
+		Config: image.Config{Width: 32, Height: 32, ColorModel: color.Palette(palette.Plan9)},
+		Delay:  []int{10, 10},
+	}
+
+	// Form frames
+	for f := 0; f < 2; f++ {
+		frame := image.NewPaletted(image.Rect(0, 0, 32, 32), palette.Plan9)
+		for n := range frame.Pix {
+			frame.Pix[n] = uint8((n*7 + f*31) % len(palette.Plan9))
+		}
+
+		g.Image = append(g.Image, frame)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := gif.EncodeAll(buf, g); err != nil {
+		panic("Error encoding image. Tests cannot continue. " + err.Error())
+	}
+
+	return buf.Bytes()
+}
+
+var _ = Describe("gif-quality.go", func() {
+	BeforeEach(func() {
+		// Initalize config instance
+		config.Init()
+	})
+
+	Describe("`newGifQuality` method", func() {
+		It("Converts qualities into compression settings", func() {
+			// Verify return values
+			Expect(newGifQuality(100)).To(Equal(gifQuality{colors: 256, lossy: 0, optimization: 1}))
+			Expect(newGifQuality(90)).To(Equal(gifQuality{colors: 256, lossy: 20, optimization: 1}))
+			Expect(newGifQuality(50)).To(Equal(gifQuality{colors: 256, lossy: 100, optimization: 2}))
+			Expect(newGifQuality(49)).To(Equal(gifQuality{colors: 232, lossy: 102, optimization: 3}))
+			Expect(newGifQuality(1)).To(Equal(gifQuality{colors: GIF_MIN_COLORS, lossy: 198, optimization: 3}))
+		})
+
+		It("Clamps qualities outside of the valid range", func() {
+			// Verify return values
+			Expect(newGifQuality(0)).To(Equal(newGifQuality(1)))
+			Expect(newGifQuality(101)).To(Equal(newGifQuality(100)))
+		})
+
+		It("Compresses more as quality drops", func() {
+			// Loop through qualities, comparing each to the quality above it
+			for val := int64(99); val > 0; val-- {
+				higher, lower := newGifQuality(val+1), newGifQuality(val)
+
+				Expect(lower.colors).To(BeNumerically("<=", higher.colors))
+				Expect(lower.lossy).To(BeNumerically(">", higher.lossy))
+				Expect(lower.optimization).To(BeNumerically(">=", higher.optimization))
+			}
+		})
+	})
+
+	Describe("`args` method", func() {
+		It("Returns arguments for the settings, omitting those that don't change the image", func() {
+			// Verify return values
+			Expect(newGifQuality(100).args()).To(Equal([]string{"-O1"}))
+			Expect(newGifQuality(1).args()).To(Equal([]string{"-O3", "--lossy=198", "--colors=2"}))
+		})
+	})
+
+	Describe("Output size", func() {
+		// sizes returns the size of a GIF processed at each of a set of qualities
+		sizes := func(qualities ...int64) []int {
+			sizes := make([]int, len(qualities))
+			for n, val := range qualities {
+				mi, err := NewGifMutableImage(&ProcessableImage{Data: mockColorfulGif(), ImageType: utils.GIF_MIME})
+				Expect(err).To(Not(HaveOccurred()))
+
+				Expect(mi.Quality(val)).To(Succeed())
+				Expect(mi.Flush()).To(Succeed())
+
+				sizes[n] = len(mi.Img().Data)
+			}
+
+			return sizes
+		}
+
+		// verifyDecreasing verifies that output sizes decrease as quality drops
+		verifyDecreasing := func() {
+			s := sizes(100, 75, 50, 25, 10, 1)
+			for n := 1; n < len(s); n++ {
+				Expect(s[n]).To(BeNumerically("<=", s[n-1]))
+			}
+
+			Expect(s[len(s)-1]).To(BeNumerically("<", s[0]))
+		}
+
+		Context("With the native engine", func() {
+			It("Decreases as quality drops", func() {
+				config.GetInstance().Images.Gif.Engine = GIF_ENGINE_NATIVE
+				verifyDecreasing()
+			})
+		})
+
+		Context("With the GIF command", func() {
+			It("Decreases as quality drops", func() {
+				// Skip when the command isn't installed
+				if _, err := exec.LookPath(GIF_COMMAND); err != nil {
+					Skip(GIF_COMMAND + " is not installed")
+				}
+
+				config.GetInstance().Images.Gif.Engine = GIF_ENGINE_GIFSICLE
+				verifyDecreasing()
+			})
+		})
+	})
+})
@@ -0,0 +1,90 @@
+// gif-webp contains all functionality around converting animated GIFs
+// to animated WebPs with the WebP command
+package mutableimages
+
+import (
+	// Standard lib
+	"io/ioutil"
+	"os"
+	"path"
+
+	// Internal
+	"github.com/marksost/img/helpers"
+	"github.com/marksost/img/metrics"
+)
+
+const (
+	// The command to exec when converting a GIF to a WebP
+	WEBP_COMMAND = "gif2webp"
+	// The command argument used to encode each frame as whichever of lossy or lossless is smaller
+	WEBP_MIXED_COMMAND = "-mixed"
+	// The command argument used to set the output file
+	WEBP_OUTPUT_COMMAND = "-o"
+	// The command argument used to set the quality of lossy frames
+	WEBP_QUALITY_COMMAND = "-q"
+)
+
+var (
+	// Path of the WebP command to run
+	// NOTE: Only changed within tests
+	webpCommandPath = WEBP_COMMAND
+
+	// Metrics recorded while running WebP commands
+	webpCommandMetrics = &commandMetrics{
+		duration: metrics.NewHistogram(
+			"img_gif2webp_duration_seconds",
+			"Time taken to run gif2webp subprocesses.",
+			metrics.DurationBuckets,
+		),
+		failures: metrics.NewCounter(
+			"img_gif2webp_failures_total",
+			"Number of gif2webp subprocesses that failed to run.",
+		),
+		timeouts: metrics.NewCounter(
+			"img_gif2webp_timeouts_total",
+			"Number of gif2webp subprocesses killed for running too long.",
+		),
+	}
+)
+
+// webpArgs returns the WebP command arguments used to convert a GIF
+// at a quality
+// NOTE: A quality of 0 or less uses the command's default quality
+func webpArgs(quality int64) []string {
+	args := []string{WEBP_MIXED_COMMAND}
+	if quality > 0 {
+		args = append(args, WEBP_QUALITY_COMMAND, helpers.Int642String(quality))
+	}
+
+	return args
+}
+
+// runWebpCommand converts GIF data to an animated WebP with the WebP command,
+// keeping the delay of each frame and the loop count
+// NOTE: The WebP command only reads and writes files, so data is passed
+// through a temporary directory
+func runWebpCommand(data []byte, args []string) ([]byte, error) {
+	// Create temporary directory, removing it once finished
+	dir, err := ioutil.TempDir("", "img-webp")
+	if err != nil {
+		return nil, err
+	}
+
+	defer os.RemoveAll(dir)
+
+	// Write input file
+	input, output := path.Join(dir, "input.gif"), path.Join(dir, "output.webp")
+	if err = ioutil.WriteFile(input, data, 0600); err != nil {
+		return nil, err
+	}
+
+	args = append([]string{WEBP_OUTPUT_COMMAND, output}, args...)
+	args = append(args, input)
+
+	// Run command
+	if _, err = runCommand(WEBP_COMMAND, webpCommandPath, args, nil, webpCommandMetrics); err != nil {
+		return nil, err
+	}
+
+	return ioutil.ReadFile(output)
+}
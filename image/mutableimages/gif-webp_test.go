@@ -0,0 +1,141 @@
+// Tests the gif-webp.go file
+package mutableimages
+
+import (
+	// Standard lib
+	"image/gif"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	// Internal
+	"github.com/marksost/img/config"
+	"github.com/marksost/img/image/utils"
+
+	// Third-party
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("gif-webp.go", func() {
+	var (
+		// Temporary directory holding a stand-in for the WebP command
+		dir string
+		// Error to use throughout testing
+		err error
+	)
+
+	// invocation returns the arguments of the last run of the stand-in command
+	invocation := func() string {
+		data, _ := ioutil.ReadFile(path.Join(dir, "args"))
+		return strings.TrimSpace(string(data))
+	}
+
+	BeforeEach(func() {
+		// Initalize config instance
+		config.Init()
+		config.GetInstance().Images.Gif.Engine = GIF_ENGINE_NATIVE
+
+		// Create a stand-in command that logs it's arguments and copies it's input file
+		// to it's output file
+		dir, err = ioutil.TempDir("", "webp-command")
+		Expect(err).To(Not(HaveOccurred()))
+
+		script := "#!/bin/sh\necho \"$@\" > " + path.Join(dir, "args") + "\neval input=\\${$#}\ncp \"$input\" \"$2\"\n"
+		Expect(ioutil.WriteFile(path.Join(dir, "gif2webp"), []byte(script), 0755)).To(Succeed())
+
+		webpCommandPath = path.Join(dir, "gif2webp")
+	})
+
+	AfterEach(func() {
+		// Reset command and remove stand-in
+		webpCommandPath = WEBP_COMMAND
+		os.RemoveAll(dir)
+	})
+
+	Describe("`webpArgs` method", func() {
+		It("Returns arguments for a quality", func() {
+			// Verify return values
+			Expect(webpArgs(0)).To(Equal([]string{WEBP_MIXED_COMMAND}))
+			Expect(webpArgs(80)).To(Equal([]string{WEBP_MIXED_COMMAND, WEBP_QUALITY_COMMAND, "80"}))
+		})
+	})
+
+	Describe("`runWebpCommand` method", func() {
+		Context("With a command that succeeds", func() {
+			It("Returns the command's output file", func() {
+				// Call method
+				data, err := runWebpCommand([]byte("foo"), webpArgs(80))
+
+				// Verify return value
+				Expect(err).To(Not(HaveOccurred()))
+				Expect(data).To(Equal([]byte("foo")))
+				Expect(invocation()).To(MatchRegexp(`^-o .*output\.webp -mixed -q 80 .*input\.gif$`))
+			})
+		})
+
+		Context("With a command that fails", func() {
+			BeforeEach(func() {
+				// Replace stand-in with a failing command
+				script := "#!/bin/sh\necho 'bad input' >&2\nexit 1\n"
+				Expect(ioutil.WriteFile(webpCommandPath, []byte(script), 0755)).To(Succeed())
+			})
+
+			It("Returns an error including the command's error output", func() {
+				// Call method
+				_, err := runWebpCommand([]byte("foo"), webpArgs(0))
+
+				// Verify return value
+				Expect(err).To(BeAssignableToTypeOf(&GifCommandError{}))
+				Expect(err.(*GifCommandError).Command).To(Equal(WEBP_COMMAND))
+				Expect(err.(*GifCommandError).Stderr).To(Equal("bad input"))
+			})
+		})
+	})
+
+	Describe("Animated WebP output", func() {
+		var (
+			// Mock GIF mutable image to test
+			mi *GifMutableImage
+		)
+
+		BeforeEach(func() {
+			// Create mock animated image
+			mi = mockGifMutableImage(mockAnimatedGif(gif.DisposalNone))
+		})
+
+		Context("With the WebP command available", func() {
+			It("Converts all frames of the image when flushed", func() {
+				// Queue conversion
+				data := mi.Img().Data
+				Expect(mi.Format(utils.WEBP_MIME)).To(Succeed())
+				Expect(mi.Quality(60)).To(Succeed())
+
+				// Verify image was not yet converted
+				Expect(mi.Img().ImageType).To(Equal(utils.GIF_MIME))
+
+				// Call method
+				Expect(mi.Flush()).To(Succeed())
+
+				// Verify image was converted, keeping it's frames
+				Expect(mi.Img().ImageType).To(Equal(utils.WEBP_MIME))
+				Expect(mi.Img().Data).To(Equal(data))
+				Expect(mi.Img().Frames).To(Equal(2))
+				Expect(invocation()).To(ContainSubstring("-mixed -q 60"))
+			})
+		})
+
+		Context("Without the WebP command available", func() {
+			BeforeEach(func() {
+				// Reset command to one that doesn't exist
+				webpCommandPath = path.Join(dir, "missing")
+			})
+
+			It("Returns an error", func() {
+				// Verify return value
+				Expect(mi.Format(utils.WEBP_MIME)).To(HaveOccurred())
+			})
+		})
+	})
+})
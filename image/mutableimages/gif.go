@@ -6,23 +6,31 @@ import (
 	// Standard lib
 	"bytes"
 	"fmt"
+	"image"
 	"image/gif"
+	"image/png"
 	"os/exec"
+	"time"
 
 	// Internal
+	"github.com/marksost/img/image/utils"
 	"github.com/marksost/img/values"
 )
 
 const (
-	// The conversion factor to use for quality operations
-	// NOTE: Quality can be anywhere from 1 to 100, so this converts
-	// it to a colors value between 2 and 256
-	GIF_QUALITY_FACTOR = 2.56
+	// The color space of all GIF images
+	// NOTE: GIF palettes are always made up of RGB colors
+	GIF_COLOR_SPACE = "srgb"
+	// The unit of time the delay between frames is stored in
+	GIF_DELAY_UNIT = 10 * time.Millisecond
+	// The min delay between frames, in hundredths of a second, when changing animation speed
+	// NOTE: Browsers slow down shorter delays to a tenth of a second
+	GIF_MIN_DELAY = 2
 	// The command to exec when manipulating a GIF
 	GIF_COMMAND = "gifsicle"
 	// The command argument used to crop a GIF
 	GIF_CROP_COMMAND = "--crop=%d,%d+%dx%d"
-	// The command argument used to change the quality of a GIF
+	// The command argument used to change the number of colors of a GIF
 	GIF_QUALITY_COMMAND = "--colors=%d"
 	// The command argument used to resize a GIF
 	GIF_RESIZE_COMMAND = "--resize=%dx%d"
@@ -30,30 +38,55 @@ const (
 
 type (
 	// Struct representing a process-able GIF image
+	// NOTE: Operations run with the GIF command are queued, and run as a single
+	// batch when the image is flushed
 	GifMutableImage struct {
+		batch       gifBatch          // The operations queued to be run with the GIF command
 		decodedData *gif.GIF          // The decoded data from the image
 		img         *ProcessableImage // The processable image struct containing all image information
+		outputType  string            // The MIME type to output the image as, if different from GIFs
+		quality     int64             // The quality requested for the image, used when converting it
 		width       int               // The current width of the image
 		height      int               // The current height of the image
 	}
+	// Struct representing operations queued to be run with a single GIF command
+	gifBatch struct {
+		crop    *values.CropValues      // The area to crop the image to, if any
+		quality *gifQuality             // The settings to compress the image with, if any
+		resize  *values.DimensionValues // The dimensions to resize the image to, if any
+	}
 )
 
 // NewGifMutableImage creates a new `GifMutableImage` and returns it
 func NewGifMutableImage(img *ProcessableImage) (*GifMutableImage, error) {
-	// Set error for use in this method
-	var err error
-
 	// Form new image
 	i := &GifMutableImage{
 		img: img,
 	}
 
+	// Verify canvas size before decoding frames
+	// NOTE: Frames only need to fit within the canvas, so a small image
+	// can declare a canvas far larger than it's data
+	cfg, err := gif.DecodeConfig(bytes.NewReader(img.Data))
+	if err != nil {
+		return nil, err
+	}
+
+	if err = validateCanvas(cfg.Width, cfg.Height, 1); err != nil {
+		return nil, err
+	}
+
 	// Attempt to decode the image
 	i.decodedData, err = gif.DecodeAll(bytes.NewBuffer(img.Data))
 	if err != nil {
 		return nil, err
 	}
 
+	// Verify size of all frames rendered onto the canvas
+	if err = validateCanvas(cfg.Width, cfg.Height, len(i.decodedData.Image)); err != nil {
+		return nil, err
+	}
+
 	// Set dimensions for the image data
 	i.SetDimensions()
 
@@ -85,43 +118,256 @@ func (i *GifMutableImage) GetHeight() int64 {
 // Crop performs a crop operation on the image
 // based on input width/height/x/y values
 func (i *GifMutableImage) Crop(vals *values.CropValues) error {
-	// Form command arguments
-	args := []string{
-		fmt.Sprintf(GIF_CROP_COMMAND, vals.X, vals.Y, vals.Width, vals.Height),
+	// Use native engine if needed
+	if useNativeGifEngine() {
+		return i.nativeCrop(vals)
 	}
 
-	// Return value of internal command call
-	return i.runCommand(args)
+	// Run queued operations first if a resize was queued
+	// NOTE: The GIF command always crops before resizing, so a crop can't
+	// be combined with an earlier resize
+	if i.batch.resize != nil {
+		if err := i.runBatch(); err != nil {
+			return err
+		}
+	}
+
+	// Queue crop, combining it with an earlier crop if needed
+	crop := *vals
+	if i.batch.crop != nil {
+		crop.X += i.batch.crop.X
+		crop.Y += i.batch.crop.Y
+	}
+
+	i.batch.crop = &crop
+	i.width, i.height = int(vals.Width), int(vals.Height)
+
+	return nil
+}
+
+// Flush runs all queued operations with a single GIF command, decoding
+// the result once, then converts the image to it's output format if needed
+func (i *GifMutableImage) Flush() error {
+	// Run queued operations
+	if err := i.runBatch(); err != nil {
+		return err
+	}
+
+	// Check for a pending conversion
+	if i.outputType == "" || i.outputType == i.img.ImageType {
+		return nil
+	}
+
+	// Convert image, keeping all frames, their delays and the loop count
+	// NOTE: The decoded GIF is kept, as it still describes the image's frames and dimensions
+	data, err := runWebpCommand(i.img.Data, webpArgs(i.quality))
+	if err != nil {
+		return err
+	}
+
+	i.img.Data = data
+	i.img.ImageType = i.outputType
+
+	return nil
+}
+
+// Format sets the MIME type the image is output as
+// NOTE: Animated images can only be output as GIFs or animated WebPs.
+// Other formats require a single frame to be selected first
+func (i *GifMutableImage) Format(mimeType string) error {
+	switch mimeType {
+	case utils.GIF_MIME:
+		i.outputType = ""
+	case utils.WEBP_MIME:
+		// Verify the WebP command is available
+		if _, err := exec.LookPath(webpCommandPath); err != nil {
+			return fmt.Errorf("Animated images can't be output as %s. %s was not found", mimeType, WEBP_COMMAND)
+		}
+
+		i.outputType = mimeType
+	default:
+		return fmt.Errorf("Animated images can't be output as %s. Select a single frame first", mimeType)
+	}
+
+	return nil
+}
+
+// Frame returns a static mutable image of a single frame of the image,
+// rendered on top of the frames before it
+// NOTE: Frames are numbered from zero
+func (i *GifMutableImage) Frame(index int) (MutableImage, error) {
+	// Run queued operations first
+	if err := i.runBatch(); err != nil {
+		return nil, err
+	}
+
+	// Verify frame exists
+	if index < 0 || index >= len(i.decodedData.Image) {
+		return nil, fmt.Errorf("Invalid frame %d. Image has %d frames", index, len(i.decodedData.Image))
+	}
+
+	// Render requested frame
+	// NOTE: Frames may only contain what changed from the previous frame,
+	// so must be drawn on top of them
+	frame := composeFrame(i.decodedData, index)
+
+	// Encode frame
+	// NOTE: PNGs are lossless and keep transparency, leaving the final
+	// encoding to the static image
+	buf := &bytes.Buffer{}
+	if err := png.Encode(buf, frame); err != nil {
+		return nil, err
+	}
+
+	// Create static image from frame
+	mi, err := NewMutableImage(buf.Bytes(), utils.PNG_MIME)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep source dimensions of the animated image
+	mi.Img().SourceWidth = i.img.SourceWidth
+	mi.Img().SourceHeight = i.img.SourceHeight
+
+	return mi, nil
+}
+
+// Loop sets the number of times the animation plays, with 0 meaning forever
+// NOTE: Only changes the image's metadata, so is run without the GIF command
+func (i *GifMutableImage) Loop(count int) error {
+	// Run queued operations first
+	if err := i.runBatch(); err != nil {
+		return err
+	}
+
+	// Set loop count
+	i.decodedData.LoopCount = loopCount(count)
+
+	return i.encode()
 }
 
 // Quality performs a quality operation on the image
 // based on input value
 func (i *GifMutableImage) Quality(val int64) error {
-	// Form command arguments
-	args := []string{
-		fmt.Sprintf(GIF_QUALITY_COMMAND, int64((float64(val) * GIF_QUALITY_FACTOR))),
+	// Store quality for use when converting the image
+	// NOTE: Converted images have their quality set by the WebP command instead
+	i.quality = val
+	if i.outputType == utils.WEBP_MIME {
+		return nil
 	}
 
-	// Return value of internal command call
-	return i.runCommand(args)
+	// Convert quality to compression settings
+	q := newGifQuality(val)
+
+	// Use native engine if needed
+	// NOTE: The native engine only supports reducing the number of colors
+	if useNativeGifEngine() {
+		return i.nativeQuality(q.colors)
+	}
+
+	// Queue compression
+	// NOTE: Images are compressed as they're output, after all other operations
+	i.batch.quality = &q
+
+	return nil
 }
 
 // Resize performs a resize operation on the image
 // based on input width/height values
 func (i *GifMutableImage) Resize(vals *values.DimensionValues) error {
-	// Form command arguments
-	args := []string{
-		fmt.Sprintf(GIF_RESIZE_COMMAND, vals.Width, vals.Height),
+	// Use native engine if needed
+	if useNativeGifEngine() {
+		return i.nativeResize(vals)
 	}
 
-	// Return value of internal command call
-	return i.runCommand(args)
+	// Queue resize, replacing an earlier resize if needed
+	// NOTE: Resizing is done from the cropped image, so only the final dimensions matter
+	resize := *vals
+	i.batch.resize = &resize
+	i.width, i.height = int(vals.Width), int(vals.Height)
+
+	return nil
+}
+
+// Speed changes the speed of the animation by a factor, dividing the delay
+// of each frame by it
+// NOTE: Only changes the image's metadata, so is run without the GIF command
+func (i *GifMutableImage) Speed(factor float64) error {
+	// Verify factor
+	if factor <= 0 {
+		return fmt.Errorf("Invalid speed %v", factor)
+	}
+
+	// Skip unchanged speeds
+	if factor == 1 {
+		return nil
+	}
+
+	// Run queued operations first
+	if err := i.runBatch(); err != nil {
+		return err
+	}
+
+	// Scale delays, keeping changed delays above the min delay
+	// NOTE: Delays the scaling doesn't change are left as they are, even when below the min delay
+	for n, delay := range i.decodedData.Delay {
+		scaled := int(float64(delay)/factor + 0.5)
+		if scaled == delay {
+			continue
+		}
+
+		if scaled < GIF_MIN_DELAY {
+			scaled = GIF_MIN_DELAY
+		}
+
+		i.decodedData.Delay[n] = scaled
+	}
+
+	return i.encode()
+}
+
+// Trim removes all frames outside of a range of frames, inclusive of it's start and end
+// NOTE: Frames are numbered from zero
+func (i *GifMutableImage) Trim(start, end int) error {
+	// Run queued operations first
+	if err := i.runBatch(); err != nil {
+		return err
+	}
+
+	// Verify frames exist
+	frames := len(i.decodedData.Image)
+	if start < 0 || start > end || end >= frames {
+		return fmt.Errorf("Invalid frames %d-%d. Image has %d frames", start, end, frames)
+	}
+
+	// Trim timing of frames
+	i.decodedData.Delay = i.decodedData.Delay[start : end+1]
+
+	// Render frames if frames are removed from the start
+	// NOTE: Frames may only contain what changed from the previous frame,
+	// so the remaining frames must be drawn on top of removed frames
+	if start > 0 {
+		return i.setFrames(composeFrames(i.decodedData)[start : end+1])
+	}
+
+	// Trim frames
+	i.decodedData.Image = i.decodedData.Image[:end+1]
+	if len(i.decodedData.Disposal) > end+1 {
+		i.decodedData.Disposal = i.decodedData.Disposal[:end+1]
+	}
+
+	return i.encode()
 }
 
 /* End operation methods */
 
 /* Begin internal property methods */
 
+// ColorSpace returns the name of the color space of the image
+func (i *GifMutableImage) ColorSpace() string {
+	return GIF_COLOR_SPACE
+}
+
 // Img returns a mutable image's processable image property
 func (i *GifMutableImage) Img() *ProcessableImage {
 	return i.img
@@ -133,40 +379,106 @@ func (i *GifMutableImage) SetDefaults() {}
 
 // SetDimensions reads in an image and sets it's dimensions
 func (i *GifMutableImage) SetDimensions() {
-	// Read bounds from image data
+	// Read bounds from image data, preferring the canvas size
+	// NOTE: Frames may be smaller than the canvas, and offset within it
 	bounds := i.decodedData.Image[0].Bounds()
+	if i.decodedData.Config.Width > 0 && i.decodedData.Config.Height > 0 {
+		bounds = image.Rect(0, 0, i.decodedData.Config.Width, i.decodedData.Config.Height)
+	}
 
 	// Set width and height
 	i.width = bounds.Dx()
 	i.height = bounds.Dy()
+
+	// Set frame details
+	i.img.Frames = len(i.decodedData.Image)
+	i.img.Animated = i.img.Frames > 1
+	i.img.LoopCount = playCount(i.decodedData.LoopCount)
+
+	i.img.Duration = 0
+	for _, delay := range i.decodedData.Delay {
+		i.img.Duration += time.Duration(delay) * GIF_DELAY_UNIT
+	}
 }
 
 /* End internal property methods */
 
 /* Begin utility methods */
 
+// loopCount converts the number of times a GIF's frames are played, with 0 meaning forever,
+// to the GIF's loop count
+// NOTE: GIFs store the number of times the animation repeats after the first play,
+// with -1 meaning no repeats
+func loopCount(plays int) int {
+	switch {
+	case plays == 0:
+		return 0
+	case plays == 1:
+		return -1
+	}
+
+	return plays - 1
+}
+
+// playCount converts the loop count of a GIF to the number of times it's frames
+// are played, with 0 meaning forever
+func playCount(loopCount int) int {
+	switch {
+	case loopCount == 0:
+		return 0
+	case loopCount < 0:
+		return 1
+	}
+
+	return loopCount + 1
+}
+
+// runBatch runs all queued operations with a single GIF command,
+// decoding the result once
+func (i *GifMutableImage) runBatch() error {
+	// Check for queued operations
+	args := i.batch.args()
+	if len(args) == 0 {
+		return nil
+	}
+
+	// Reset queue and run command
+	i.batch = gifBatch{}
+
+	return i.runCommand(args)
+}
+
+// args returns the GIF command arguments for all queued operations,
+// in the order the command applies them
+func (b *gifBatch) args() []string {
+	args := make([]string, 0, 5)
+
+	if b.crop != nil {
+		args = append(args, fmt.Sprintf(GIF_CROP_COMMAND, b.crop.X, b.crop.Y, b.crop.Width, b.crop.Height))
+	}
+
+	if b.resize != nil {
+		args = append(args, fmt.Sprintf(GIF_RESIZE_COMMAND, b.resize.Width, b.resize.Height))
+	}
+
+	if b.quality != nil {
+		args = append(args, b.quality.args()...)
+	}
+
+	return args
+}
+
 // runCommand runs a GIF command on the host system, with one or more arguments passed in
 // and returns the data returned by the command when possible
 func (i *GifMutableImage) runCommand(args []string) error {
-	var (
-		// Set error for use in this method
-		err error
-		// Command output
-		output bytes.Buffer
-	)
-
-	// Form command
-	cmd := exec.Command(GIF_COMMAND, args...)
-	cmd.Stdin = bytes.NewReader(i.img.Data)
-	cmd.Stdout = &output
-
 	// Run command
-	if err = cmd.Run(); err != nil {
+	data, err := runGifCommand(i.img.Data, args)
+	if err != nil {
 		return err
 	}
 
 	// Reset image data and attempt to decode the image
-	i.img.Data = output.Bytes()
+	i.img.Data = data
 	if i.decodedData, err = gif.DecodeAll(bytes.NewBuffer(i.img.Data)); err != nil {
 		return err
 	}
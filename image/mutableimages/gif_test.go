@@ -3,12 +3,19 @@ package mutableimages
 
 import (
 	// Standard lib
+	"bytes"
+	"image/gif"
+	"image/png"
 	"io/ioutil"
+	"os"
 	"path"
+	"strings"
+	"time"
 
 	// Internal
 	"github.com/marksost/img/config"
 	"github.com/marksost/img/image/utils"
+	"github.com/marksost/img/values"
 
 	// Third-party
 	. "github.com/onsi/ginkgo"
@@ -60,6 +67,45 @@ var _ = Describe("gif.go", func() {
 			Expect(mi.GetWidth()).To(BeEquivalentTo(1))  // NOTE: Equiv because of int vs int64
 			Expect(mi.GetHeight()).To(BeEquivalentTo(1)) // NOTE: Equiv because of int vs int64
 		})
+
+		Context("With a canvas larger than allowed", func() {
+			It("Returns an error", func() {
+				// Create image with a small frame on an oversized canvas
+				g := mockAnimatedGif(gif.DisposalNone)
+				g.Config.Width, g.Config.Height = 65535, 65535
+
+				buf := &bytes.Buffer{}
+				Expect(gif.EncodeAll(buf, g)).To(Succeed())
+
+				// Call method
+				_, err := NewGifMutableImage(&ProcessableImage{Data: buf.Bytes(), ImageType: utils.GIF_MIME})
+
+				// Verify return value
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("With more frames than allowed for the canvas", func() {
+			It("Returns an error", func() {
+				// Create image with many small frames on a large canvas
+				g := mockAnimatedGif(gif.DisposalNone)
+				g.Config.Width, g.Config.Height = 7000, 7000
+				for len(g.Image) <= MAX_TOTAL_PIXELS/(7000*7000) {
+					g.Image = append(g.Image, g.Image[1])
+					g.Delay = append(g.Delay, 10)
+					g.Disposal = append(g.Disposal, gif.DisposalNone)
+				}
+
+				buf := &bytes.Buffer{}
+				Expect(gif.EncodeAll(buf, g)).To(Succeed())
+
+				// Call method
+				_, err := NewGifMutableImage(&ProcessableImage{Data: buf.Bytes(), ImageType: utils.GIF_MIME})
+
+				// Verify return value
+				Expect(err).To(HaveOccurred())
+			})
+		})
 	})
 
 	Describe("MutableImage interface methods", func() {
@@ -83,6 +129,13 @@ var _ = Describe("gif.go", func() {
 			})
 		})
 
+		Describe("`ColorSpace` method", func() {
+			It("Returns the color space of the image", func() {
+				// Verify return value
+				Expect(mi.ColorSpace()).To(Equal(GIF_COLOR_SPACE))
+			})
+		})
+
 		Describe("`Img` method", func() {
 			It("Returns an internal `img` property", func() {
 				// Call method
@@ -116,6 +169,261 @@ var _ = Describe("gif.go", func() {
 				// Verify dimensions were not set
 				Expect(mi.width).To(BeEquivalentTo(1))  // NOTE: Equiv because of int vs int64
 				Expect(mi.height).To(BeEquivalentTo(1)) // NOTE: Equiv because of int vs int64
+
+				// Verify frame details were set
+				Expect(mi.Img().Animated).To(BeFalse())
+				Expect(mi.Img().Frames).To(Equal(1))
+			})
+
+			Context("With an animated image", func() {
+				BeforeEach(func() {
+					// Create mock animated image
+					mi = mockGifMutableImage(mockAnimatedGif(gif.DisposalNone))
+				})
+
+				It("Sets frame details", func() {
+					// Call method
+					mi.SetDimensions()
+
+					// Verify frame details were set
+					Expect(mi.Img().Animated).To(BeTrue())
+					Expect(mi.Img().Duration).To(Equal(300 * time.Millisecond))
+					Expect(mi.Img().Frames).To(Equal(2))
+					Expect(mi.Img().LoopCount).To(Equal(4))
+				})
+			})
+		})
+
+		Describe("`Format` method", func() {
+			Context("With the GIF format", func() {
+				It("Returns no error", func() {
+					// Verify return value
+					Expect(mi.Format(utils.GIF_MIME)).To(Succeed())
+				})
+			})
+
+			Context("With a static format", func() {
+				It("Returns an error", func() {
+					// Verify return value
+					Expect(mi.Format(utils.PNG_MIME)).To(HaveOccurred())
+				})
+			})
+		})
+
+		Describe("`Frame` method", func() {
+			BeforeEach(func() {
+				// Create mock animated image
+				mi = mockGifMutableImage(mockAnimatedGif(gif.DisposalNone))
+				mi.Img().SourceWidth, mi.Img().SourceHeight = 4, 4
+			})
+
+			Context("With a frame within the image", func() {
+				It("Returns a static image of the frame drawn over previous frames", func() {
+					// Call method
+					frame, err := mi.Frame(1)
+
+					// Verify return value
+					Expect(err).To(Not(HaveOccurred()))
+					Expect(frame.Img().Animated).To(BeFalse())
+					Expect(frame.Img().ImageType).To(Equal(utils.PNG_MIME))
+					Expect(frame.Img().SourceWidth).To(BeEquivalentTo(4))
+					Expect(frame.Img().SourceHeight).To(BeEquivalentTo(4))
+
+					// Verify frame data
+					img, err := png.Decode(bytes.NewReader(frame.Img().Data))
+					Expect(err).To(Not(HaveOccurred()))
+					Expect(img.At(0, 0)).To(BeEquivalentTo(red))
+					Expect(img.At(3, 3)).To(BeEquivalentTo(blue))
+				})
+			})
+
+			Context("With a frame outside of the image", func() {
+				It("Returns an error", func() {
+					// Call method
+					_, err := mi.Frame(2)
+
+					// Verify return value
+					Expect(err).To(HaveOccurred())
+				})
+			})
+		})
+
+		Describe("Animation methods", func() {
+			BeforeEach(func() {
+				// Create mock animated image
+				mi = mockGifMutableImage(mockAnimatedGif(gif.DisposalNone))
+			})
+
+			Describe("`Loop` method", func() {
+				It("Sets the loop count of the image", func() {
+					// Verify loop counts for play counts
+					for count, loopCount := range map[int]int{0: 0, 1: -1, 5: 4} {
+						Expect(mi.Loop(count)).To(Succeed())
+
+						g, _ := decodeFrames(mi)
+						Expect(g.LoopCount).To(Equal(loopCount))
+					}
+				})
+			})
+
+			Describe("`Speed` method", func() {
+				Context("With a valid factor", func() {
+					It("Scales the delay of each frame, keeping them above the min delay", func() {
+						// Call method
+						Expect(mi.Speed(8)).To(Succeed())
+
+						// Verify delays
+						g, _ := decodeFrames(mi)
+						Expect(g.Delay).To(Equal([]int{GIF_MIN_DELAY, 3}))
+					})
+				})
+
+				Context("With delays below the min delay", func() {
+					BeforeEach(func() {
+						// Create mock image with short delays
+						g := mockAnimatedGif(gif.DisposalNone)
+						g.Delay = []int{0, 1}
+
+						mi = mockGifMutableImage(g)
+					})
+
+					It("Leaves delays the factor doesn't change", func() {
+						// Verify delays are unchanged at the same speed
+						data := mi.Img().Data
+						Expect(mi.Speed(1)).To(Succeed())
+						Expect(mi.Img().Data).To(Equal(data))
+
+						// Verify only changed delays are kept above the min delay
+						Expect(mi.Speed(0.5)).To(Succeed())
+
+						g, _ := decodeFrames(mi)
+						Expect(g.Delay).To(Equal([]int{0, GIF_MIN_DELAY}))
+					})
+				})
+
+				Context("With an invalid factor", func() {
+					It("Returns an error", func() {
+						// Verify return value
+						Expect(mi.Speed(0)).To(HaveOccurred())
+					})
+				})
+			})
+
+			Describe("`Trim` method", func() {
+				Context("With frames removed from the end", func() {
+					It("Keeps the frames within the range", func() {
+						// Call method
+						Expect(mi.Trim(0, 0)).To(Succeed())
+
+						// Verify frames
+						g, frames := decodeFrames(mi)
+						Expect(frames).To(HaveLen(1))
+						Expect(frames[0].RGBAAt(3, 3)).To(Equal(red))
+						Expect(g.Delay).To(Equal([]int{10}))
+						Expect(mi.Img().Frames).To(Equal(1))
+					})
+				})
+
+				Context("With frames removed from the start", func() {
+					It("Draws the remaining frames on top of the removed frames", func() {
+						// Call method
+						Expect(mi.Trim(1, 1)).To(Succeed())
+
+						// Verify frames
+						g, frames := decodeFrames(mi)
+						Expect(frames).To(HaveLen(1))
+						Expect(frames[0].RGBAAt(0, 0)).To(Equal(red))
+						Expect(frames[0].RGBAAt(3, 3)).To(Equal(blue))
+						Expect(g.Delay).To(Equal([]int{20}))
+					})
+				})
+
+				Context("With frames outside of the image", func() {
+					It("Returns an error", func() {
+						// Verify return value
+						Expect(mi.Trim(1, 2)).To(HaveOccurred())
+						Expect(mi.Trim(1, 0)).To(HaveOccurred())
+					})
+				})
+			})
+		})
+	})
+
+	Describe("`playCount` method", func() {
+		It("Converts loop counts to play counts", func() {
+			// Verify return values
+			Expect(playCount(0)).To(Equal(0))
+			Expect(playCount(-1)).To(Equal(1))
+			Expect(playCount(3)).To(Equal(4))
+		})
+	})
+
+	Describe("Batched operation methods", func() {
+		var (
+			// Temporary directory holding a stand-in for the GIF command
+			dir string
+		)
+
+		// invocations returns the arguments of each run of the stand-in command
+		invocations := func() []string {
+			data, _ := ioutil.ReadFile(path.Join(dir, "args"))
+			return strings.Split(strings.TrimSpace(string(data)), "\n")
+		}
+
+		BeforeEach(func() {
+			// Use the GIF command
+			config.GetInstance().Images.Gif.Engine = GIF_ENGINE_GIFSICLE
+
+			// Create a stand-in command that logs it's arguments and outputs it's input
+			dir, err = ioutil.TempDir("", "gif-command")
+			Expect(err).To(Not(HaveOccurred()))
+
+			script := "#!/bin/sh\necho \"$@\" >> " + path.Join(dir, "args") + "\ncat\n"
+			Expect(ioutil.WriteFile(path.Join(dir, "gifsicle"), []byte(script), 0755)).To(Succeed())
+
+			gifCommandPath = path.Join(dir, "gifsicle")
+		})
+
+		AfterEach(func() {
+			// Reset command and remove stand-in
+			gifCommandPath = GIF_COMMAND
+			os.RemoveAll(dir)
+		})
+
+		Context("With operations that can be combined", func() {
+			It("Queues operations and runs them with a single command when flushed", func() {
+				// Queue operations
+				Expect(mi.Crop(&values.CropValues{X: 0, Y: 0, Width: 1, Height: 1})).To(Succeed())
+				Expect(mi.Crop(&values.CropValues{X: 0, Y: 0, Width: 1, Height: 1})).To(Succeed())
+				Expect(mi.Resize(&values.DimensionValues{Width: 1, Height: 1})).To(Succeed())
+				Expect(mi.Quality(50)).To(Succeed())
+
+				// Verify nothing was run, but dimensions were updated
+				Expect(invocations()).To(Equal([]string{""}))
+				Expect(mi.GetWidth()).To(Equal(int64(1)))
+				Expect(mi.GetHeight()).To(Equal(int64(1)))
+
+				// Call method
+				Expect(mi.Flush()).To(Succeed())
+
+				// Verify a single command was run
+				Expect(invocations()).To(Equal([]string{"--crop=0,0+1x1 --resize=1x1 -O2 --lossy=100"}))
+
+				// Verify flushing again runs nothing
+				Expect(mi.Flush()).To(Succeed())
+				Expect(invocations()).To(HaveLen(1))
+			})
+		})
+
+		Context("With a crop following a resize", func() {
+			It("Runs the resize before queueing the crop", func() {
+				// Queue operations
+				Expect(mi.Resize(&values.DimensionValues{Width: 1, Height: 1})).To(Succeed())
+				Expect(mi.Crop(&values.CropValues{X: 0, Y: 0, Width: 1, Height: 1})).To(Succeed())
+				Expect(mi.Flush()).To(Succeed())
+
+				// Verify commands were run in order
+				Expect(invocations()).To(Equal([]string{"--resize=1x1", "--crop=0,0+1x1"}))
 			})
 		})
 	})
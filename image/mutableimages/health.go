@@ -0,0 +1,91 @@
+// health contains all functionality around verifying that the tools
+// used to process images are available and working
+package mutableimages
+
+import (
+	// Standard lib
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/png"
+	"os/exec"
+	"time"
+
+	// Third-party
+	"github.com/h2non/bimg"
+)
+
+const (
+	// Max time allowed for a health check command to run
+	HEALTH_CHECK_TIMEOUT = 5 * time.Second
+)
+
+// CheckGif verifies that the GIF command is available on the host system
+// and can be run
+// NOTE: Always passes when GIFs are processed with the native engine instead
+func CheckGif() error {
+	// Skip check when the command isn't used
+	if useNativeGifEngine() {
+		return nil
+	}
+
+	// Look up command
+	path, err := exec.LookPath(GIF_COMMAND)
+	if err != nil {
+		return fmt.Errorf("%s was not found on PATH", GIF_COMMAND)
+	}
+
+	// Run command with a timeout
+	ctx, cancel := context.WithTimeout(context.Background(), HEALTH_CHECK_TIMEOUT)
+	defer cancel()
+
+	if err = exec.CommandContext(ctx, path, "--version").Run(); err != nil {
+		return fmt.Errorf("%s failed to run: %s", GIF_COMMAND, err.Error())
+	}
+
+	return nil
+}
+
+// CheckWebp verifies that the WebP command, used to output animated
+// images as WebPs, is available on the host system and can be run
+func CheckWebp() error {
+	// Look up command
+	path, err := exec.LookPath(webpCommandPath)
+	if err != nil {
+		return fmt.Errorf("%s was not found on PATH", WEBP_COMMAND)
+	}
+
+	// Run command with a timeout
+	ctx, cancel := context.WithTimeout(context.Background(), HEALTH_CHECK_TIMEOUT)
+	defer cancel()
+
+	if err = exec.CommandContext(ctx, path, "-version").Run(); err != nil {
+		return fmt.Errorf("%s failed to run: %s", WEBP_COMMAND, err.Error())
+	}
+
+	return nil
+}
+
+// WebpRequired returns a boolean indicating if the WebP command is needed to process
+// images based on configuration, rather than only when WebP output is requested
+func WebpRequired() bool {
+	return apngMode() == APNG_MODE_WEBP
+}
+
+// CheckStatic verifies that static images can be processed, by encoding
+// a tiny generated image through libvips
+func CheckStatic() error {
+	// Generate a 1x1 PNG
+	buf := &bytes.Buffer{}
+	if err := png.Encode(buf, image.NewRGBA(image.Rect(0, 0, 1, 1))); err != nil {
+		return err
+	}
+
+	// Attempt to convert the image to a JPEG
+	if _, err := bimg.NewImage(buf.Bytes()).Convert(bimg.JPEG); err != nil {
+		return fmt.Errorf("libvips failed to encode an image: %s", err.Error())
+	}
+
+	return nil
+}
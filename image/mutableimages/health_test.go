@@ -0,0 +1,99 @@
+// Tests the health.go file
+package mutableimages
+
+import (
+	// Standard lib
+	"os/exec"
+
+	// Internal
+	"github.com/marksost/img/config"
+
+	// Third-party
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("health.go", func() {
+	Describe("`CheckGif` method", func() {
+		BeforeEach(func() {
+			// Initalize config instance
+			config.Init()
+		})
+
+		Context("When GIFs are processed with the GIF command", func() {
+			BeforeEach(func() {
+				// Set engine
+				config.GetInstance().Images.Gif.Engine = GIF_ENGINE_GIFSICLE
+			})
+
+			It("Returns an error only when the GIF command is unavailable", func() {
+				// Check for command on the host system
+				_, lookupErr := exec.LookPath(GIF_COMMAND)
+
+				// Call method
+				err := CheckGif()
+
+				// Verify return value
+				if lookupErr != nil {
+					Expect(err).To(HaveOccurred())
+				} else {
+					Expect(err).To(Not(HaveOccurred()))
+				}
+			})
+		})
+
+		Context("When GIFs are processed with the native engine", func() {
+			BeforeEach(func() {
+				// Set engine
+				config.GetInstance().Images.Gif.Engine = GIF_ENGINE_NATIVE
+			})
+
+			It("Returns no error", func() {
+				// Verify return value
+				Expect(CheckGif()).To(Succeed())
+			})
+		})
+	})
+
+	Describe("`CheckWebp` method", func() {
+		It("Returns an error only when the WebP command is unavailable", func() {
+			// Check for command on the host system
+			_, lookupErr := exec.LookPath(WEBP_COMMAND)
+
+			// Call method
+			err := CheckWebp()
+
+			// Verify return value
+			if lookupErr != nil {
+				Expect(err).To(HaveOccurred())
+			} else {
+				Expect(err).To(Not(HaveOccurred()))
+			}
+		})
+	})
+
+	Describe("`WebpRequired` method", func() {
+		BeforeEach(func() {
+			// Initalize config instance
+			config.Init()
+		})
+
+		It("Returns true only when animated PNGs are output as WebPs", func() {
+			// Verify return values
+			Expect(WebpRequired()).To(BeFalse())
+
+			config.GetInstance().Images.Apng.Mode = APNG_MODE_WEBP
+			Expect(WebpRequired()).To(BeTrue())
+		})
+	})
+
+	Describe("`CheckStatic` method", func() {
+		It("Encodes an image and returns no error", func() {
+			// Call method
+			err := CheckStatic()
+
+			// Verify return value
+			Expect(err).To(Not(HaveOccurred()))
+		})
+	})
+})
@@ -5,12 +5,21 @@ package mutableimages
 import (
 	// Standard lib
 	"fmt"
+	"time"
 
 	// Internal
 	"github.com/marksost/img/image/utils"
 	"github.com/marksost/img/values"
 )
 
+const (
+	// Max number of pixels allowed in the canvas of an animated image
+	MAX_CANVAS_PIXELS = 50000000
+	// Max number of pixels allowed across all rendered frames of an animated image
+	// NOTE: Frames may be rendered onto full copies of the canvas
+	MAX_TOTAL_PIXELS = 500000000
+)
+
 type (
 	// Interface describing methods used to process an image
 	MutableImage interface {
@@ -20,24 +29,45 @@ type (
 
 		// Operation methods
 		Crop(*values.CropValues) error
+		Flush() error
+		Format(string) error
+		Frame(int) (MutableImage, error)
+		Loop(int) error
 		Quality(int64) error
 		Resize(*values.DimensionValues) error
+		Speed(float64) error
+		Trim(int, int) error
 
 		// Internal property methods
+		ColorSpace() string
 		Img() *ProcessableImage
 		SetDefaults()
 		SetDimensions()
 	}
 	// Struct representing a set of dta to be used when processing mutable images
 	ProcessableImage struct {
-		Animated     bool   // Whether the image is animated or not
-		Data         []byte // Image data to be used for processing
-		ImageType    string // The MIME type of the image
-		SourceWidth  int64  // The initial width of the image
-		SourceHeight int64  // The initial height of the image
+		Animated     bool          // Whether the image is animated or not, meaning it has more than one frame
+		Data         []byte        // Image data to be used for processing
+		Duration     time.Duration // The time taken to play all frames of the image once
+		Frames       int           // The number of frames in the image
+		ImageType    string        // The MIME type of the image
+		LoopCount    int           // The number of times the image's frames are played (0 for forever)
+		SourceWidth  int64         // The initial width of the image
+		SourceHeight int64         // The initial height of the image
 	}
 )
 
+// IsSupportedType returns a boolean indicating if mutable images
+// can be created for images of a MIME type
+func IsSupportedType(imageType string) bool {
+	switch imageType {
+	case utils.APNG_MIME, utils.GIF_MIME, utils.JPEG_MIME, utils.PNG_MIME, utils.TIFF_MIME:
+		return true
+	}
+
+	return false
+}
+
 // NewMutableImage creates a new `MutableImage` and returns it
 func NewMutableImage(data []byte, imageType string) (MutableImage, error) {
 	var (
@@ -47,7 +77,6 @@ func NewMutableImage(data []byte, imageType string) (MutableImage, error) {
 		mi MutableImage
 		// Create processable image
 		pi *ProcessableImage = &ProcessableImage{
-			Animated:  imageType == utils.GIF_MIME,
 			Data:      data,
 			ImageType: imageType,
 		}
@@ -55,6 +84,12 @@ func NewMutableImage(data []byte, imageType string) (MutableImage, error) {
 
 	// Generate mutable image based on image type
 	switch imageType {
+	case utils.APNG_MIME:
+		// Create mutable image based on how animated PNGs are handled
+		mi, err = newApngMutableImage(pi)
+		if err != nil {
+			return nil, err
+		}
 	case utils.GIF_MIME:
 		// Create GIF mutable image
 		mi, err = NewGifMutableImage(pi)
@@ -80,3 +115,21 @@ func NewMutableImage(data []byte, imageType string) (MutableImage, error) {
 
 	return mi, nil
 }
+
+// validateCanvas verifies the canvas of an animated image, and all of it's frames
+// rendered onto copies of it, are within size limits
+// NOTE: Dimensions are read from images, so can't be trusted before they're verified
+func validateCanvas(width, height, frames int) error {
+	// Verify canvas size
+	pixels := int64(width) * int64(height)
+	if width <= 0 || height <= 0 || pixels > MAX_CANVAS_PIXELS {
+		return fmt.Errorf("Image dimensions %dx%d are outside of the allowed range", width, height)
+	}
+
+	// Verify size of all frames
+	if pixels*int64(frames) > MAX_TOTAL_PIXELS {
+		return fmt.Errorf("Image has too many frames (%d) of %dx%d to process", frames, width, height)
+	}
+
+	return nil
+}
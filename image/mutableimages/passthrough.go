@@ -0,0 +1,158 @@
+// passthrough contains all functionality around images that are served
+// as they were downloaded, without any processing
+// NOTE: Used for animated PNGs, when configured to pass them through
+package mutableimages
+
+import (
+	// Standard lib
+	"fmt"
+	"time"
+
+	// Internal
+	"github.com/marksost/img/values"
+)
+
+var (
+	// Error returned for operations requested on pass-through images
+	ErrPassthrough = fmt.Errorf("Animated PNGs are served without processing")
+)
+
+type (
+	// Struct representing an image served without processing
+	PassthroughMutableImage struct {
+		img    *ProcessableImage // The processable image struct containing all image information
+		width  int               // The width of the image
+		height int               // The height of the image
+	}
+)
+
+// NewPassthroughMutableImage creates a new `PassthroughMutableImage` and returns it
+func NewPassthroughMutableImage(img *ProcessableImage) (*PassthroughMutableImage, error) {
+	// Form new image
+	i := &PassthroughMutableImage{
+		img: img,
+	}
+
+	// Verify image can be read
+	if _, err := readApng(img.Data); err != nil {
+		return nil, err
+	}
+
+	// Set dimensions for the image data
+	i.SetDimensions()
+
+	return i, nil
+}
+
+/* Begin dimension methods */
+
+// GetWidth returns the width of the image
+func (i *PassthroughMutableImage) GetWidth() int64 {
+	return int64(i.width)
+}
+
+// GetHeight returns the height of the image
+func (i *PassthroughMutableImage) GetHeight() int64 {
+	return int64(i.height)
+}
+
+/* End dimension methods */
+
+/* Begin operation methods */
+
+// Crop always returns an error, as pass-through images aren't processed
+func (i *PassthroughMutableImage) Crop(vals *values.CropValues) error {
+	return ErrPassthrough
+}
+
+// Flush runs any queued operations on the image
+// NOTE: Pass-through images aren't processed, so never have queued operations
+func (i *PassthroughMutableImage) Flush() error {
+	return nil
+}
+
+// Format sets the MIME type the image is output as
+// NOTE: Pass-through images can only be output as they were downloaded
+func (i *PassthroughMutableImage) Format(mimeType string) error {
+	if mimeType != i.img.ImageType {
+		return ErrPassthrough
+	}
+
+	return nil
+}
+
+// Frame always returns an error, as pass-through images aren't processed
+func (i *PassthroughMutableImage) Frame(index int) (MutableImage, error) {
+	return nil, ErrPassthrough
+}
+
+// Loop always returns an error, as pass-through images aren't processed
+func (i *PassthroughMutableImage) Loop(count int) error {
+	return ErrPassthrough
+}
+
+// Quality performs a quality operation on the image
+// NOTE: Quality operations are run on all images, so are ignored rather than
+// returning an error
+func (i *PassthroughMutableImage) Quality(val int64) error {
+	return nil
+}
+
+// Resize always returns an error, as pass-through images aren't processed
+func (i *PassthroughMutableImage) Resize(vals *values.DimensionValues) error {
+	return ErrPassthrough
+}
+
+// Speed always returns an error, as pass-through images aren't processed
+func (i *PassthroughMutableImage) Speed(factor float64) error {
+	return ErrPassthrough
+}
+
+// Trim always returns an error, as pass-through images aren't processed
+func (i *PassthroughMutableImage) Trim(start, end int) error {
+	return ErrPassthrough
+}
+
+/* End operation methods */
+
+/* Begin internal property methods */
+
+// ColorSpace returns the name of the color space of the image
+// NOTE: Pass-through images aren't decoded, so always return an empty string
+func (i *PassthroughMutableImage) ColorSpace() string {
+	return ""
+}
+
+// Img returns a mutable image's processable image property
+func (i *PassthroughMutableImage) Img() *ProcessableImage {
+	return i.img
+}
+
+// SetDefault is used to set any needed default values for the mutable image
+// before image processing starts
+func (i *PassthroughMutableImage) SetDefaults() {}
+
+// SetDimensions reads in an image and sets it's dimensions
+func (i *PassthroughMutableImage) SetDimensions() {
+	// Read structure from image data
+	a, err := readApng(i.img.Data)
+	if err != nil {
+		return
+	}
+
+	// Set width and height
+	i.width = a.width
+	i.height = a.height
+
+	// Set frame details
+	i.img.Frames = len(a.frames)
+	i.img.Animated = i.img.Frames > 1
+	i.img.LoopCount = a.plays
+
+	i.img.Duration = 0
+	for _, frame := range a.frames {
+		i.img.Duration += time.Duration(frame.delay) * GIF_DELAY_UNIT
+	}
+}
+
+/* End internal property methods */
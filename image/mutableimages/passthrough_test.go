@@ -0,0 +1,77 @@
+// Tests the passthrough.go file
+package mutableimages
+
+import (
+	// Standard lib
+	"time"
+
+	// Internal
+	"github.com/marksost/img/image/utils"
+	"github.com/marksost/img/values"
+
+	// Third-party
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("passthrough.go", func() {
+	var (
+		// Mock pass-through mutable image to test
+		mi *PassthroughMutableImage
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		// Create pass-through mutable image
+		mi, err = NewPassthroughMutableImage(&ProcessableImage{Data: mockApng(3), ImageType: utils.PNG_MIME})
+		if err != nil {
+			panic("Error creating pass-through mutable image. Tests cannot continue. " + err.Error())
+		}
+	})
+
+	Describe("`NewPassthroughMutableImage` method", func() {
+		Context("With invalid image data", func() {
+			It("Returns an error", func() {
+				// Call method
+				_, err := NewPassthroughMutableImage(&ProcessableImage{Data: []byte("foo")})
+
+				// Verify return value
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("With an animated PNG", func() {
+			It("Sets the image's dimensions and frame details", func() {
+				// Verify dimensions and frame details
+				Expect(mi.GetWidth()).To(BeEquivalentTo(4))
+				Expect(mi.GetHeight()).To(BeEquivalentTo(4))
+				Expect(mi.Img().Animated).To(BeTrue())
+				Expect(mi.Img().Duration).To(Equal(300 * time.Millisecond))
+				Expect(mi.Img().Frames).To(Equal(2))
+				Expect(mi.Img().LoopCount).To(Equal(3))
+			})
+		})
+	})
+
+	Describe("MutableImage interface methods", func() {
+		It("Ignores quality and flushes", func() {
+			// Verify return values
+			Expect(mi.Quality(50)).To(Succeed())
+			Expect(mi.Flush()).To(Succeed())
+			Expect(mi.Format(utils.PNG_MIME)).To(Succeed())
+		})
+
+		It("Returns errors for all other operations", func() {
+			// Verify return values
+			_, err := mi.Frame(0)
+			Expect(err).To(Equal(ErrPassthrough))
+			Expect(mi.Crop(&values.CropValues{Width: 1, Height: 1})).To(Equal(ErrPassthrough))
+			Expect(mi.Format(utils.WEBP_MIME)).To(Equal(ErrPassthrough))
+			Expect(mi.Loop(0)).To(Equal(ErrPassthrough))
+			Expect(mi.Resize(&values.DimensionValues{Width: 1, Height: 1})).To(Equal(ErrPassthrough))
+			Expect(mi.Speed(2)).To(Equal(ErrPassthrough))
+			Expect(mi.Trim(0, 0)).To(Equal(ErrPassthrough))
+		})
+	})
+})
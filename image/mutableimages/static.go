@@ -4,14 +4,27 @@
 package mutableimages
 
 import (
+	// Standard lib
+	"fmt"
+
 	// Internal
 	"github.com/marksost/img/config"
+	"github.com/marksost/img/image/utils"
 	"github.com/marksost/img/values"
 
 	// Third-party
 	"github.com/h2non/bimg"
 )
 
+var (
+	// Map of MIME types static images can be output as to their libvips image types
+	staticOutputTypes = map[string]bimg.ImageType{
+		utils.JPEG_MIME: bimg.JPEG,
+		utils.PNG_MIME:  bimg.PNG,
+		utils.WEBP_MIME: bimg.WEBP,
+	}
+)
+
 type (
 	// Struct representing a process-able "static" image
 	StaticMutableImage struct {
@@ -19,6 +32,8 @@ type (
 		img *ProcessableImage
 		// Max-width of the image before switching interpolators
 		interpolatorThreshold int64
+		// The MIME type to output the image as, if different from it's source
+		outputType string
 		// The current width of the image
 		width int
 		// The current height of the image
@@ -82,6 +97,12 @@ func (i *StaticMutableImage) Crop(vals *values.CropValues) error {
 	return i.resize(opts)
 }
 
+// Loop sets the number of times the animation plays
+// NOTE: Static images aren't animated, so always return an error
+func (i *StaticMutableImage) Loop(count int) error {
+	return fmt.Errorf("Static images can't have their loop count changed")
+}
+
 // Quality performs a quality operation on the image
 // based on input value
 func (i *StaticMutableImage) Quality(val int64) error {
@@ -96,6 +117,42 @@ func (i *StaticMutableImage) Quality(val int64) error {
 	return i.resize(opts)
 }
 
+// Flush runs any queued operations on the image
+// NOTE: Operations on static images are run immediately, so are never queued,
+// other than converting the image to it's output format when not yet done
+func (i *StaticMutableImage) Flush() error {
+	// Check for a pending conversion
+	if i.outputType == "" || i.outputType == i.img.ImageType {
+		return nil
+	}
+
+	// Return value of internal resize call
+	return i.resize(bimg.Options{Quality: 100})
+}
+
+// Format sets the MIME type the image is output as
+// NOTE: The image is converted the next time it's data is processed
+func (i *StaticMutableImage) Format(mimeType string) error {
+	// Verify format can be output
+	if _, ok := staticOutputTypes[mimeType]; !ok {
+		return fmt.Errorf("Static images can't be output as %s", mimeType)
+	}
+
+	i.outputType = mimeType
+
+	return nil
+}
+
+// Frame returns a static mutable image of a single frame of the image
+// NOTE: Static images only have a single frame, so are returned as-is
+func (i *StaticMutableImage) Frame(index int) (MutableImage, error) {
+	if index != 0 {
+		return nil, fmt.Errorf("Invalid frame %d. Image has 1 frame", index)
+	}
+
+	return i, nil
+}
+
 // Resize performs a resize operation on the image
 // based on input width/height values
 func (i *StaticMutableImage) Resize(vals *values.DimensionValues) error {
@@ -117,10 +174,34 @@ func (i *StaticMutableImage) Resize(vals *values.DimensionValues) error {
 	return i.resize(opts)
 }
 
+// Speed changes the speed of the animation
+// NOTE: Static images aren't animated, so always return an error
+func (i *StaticMutableImage) Speed(factor float64) error {
+	return fmt.Errorf("Static images can't have their speed changed")
+}
+
+// Trim removes all frames outside of a range of frames
+// NOTE: Static images aren't animated, so always return an error
+func (i *StaticMutableImage) Trim(start, end int) error {
+	return fmt.Errorf("Static images can't have their frames trimmed")
+}
+
 /* End operation methods */
 
 /* Begin internal property methods */
 
+// ColorSpace returns the name of the color space of the image, as reported by libvips
+// NOTE: Returns an empty string if the image's metadata can't be read
+func (i *StaticMutableImage) ColorSpace() string {
+	// Read metadata from image data
+	meta, err := bimg.Metadata(i.img.Data)
+	if err != nil {
+		return ""
+	}
+
+	return meta.Space
+}
+
 // Img returns a mutable image's processable image property
 func (i *StaticMutableImage) Img() *ProcessableImage {
 	return i.img
@@ -144,6 +225,13 @@ func (i *StaticMutableImage) SetDimensions() {
 	// Set width and height
 	i.width = size.Width
 	i.height = size.Height
+
+	// Set frame details
+	// NOTE: Static images always have a single frame, played once
+	i.img.Animated = false
+	i.img.Duration = 0
+	i.img.Frames = 1
+	i.img.LoopCount = 1
 }
 
 /* End internal property methods */
@@ -153,14 +241,22 @@ func (i *StaticMutableImage) SetDimensions() {
 // resize takes a set of bimg options and calls for a `resize` on the image data
 // NOTE: `resize` handles more than just resizing of an image (ex: cropping)
 func (i *StaticMutableImage) resize(opts bimg.Options) error {
+	// Set output type if needed
+	if i.outputType != "" {
+		opts.Type = staticOutputTypes[i.outputType]
+	}
+
 	// Resize image with opts
 	data, err := bimg.Resize(i.img.Data, opts)
 	if err != nil {
 		return err
 	}
 
-	// Reset image data
+	// Reset image data and type
 	i.img.Data = data
+	if i.outputType != "" {
+		i.img.ImageType = i.outputType
+	}
 
 	// Reset dimensions for the image data
 	i.SetDimensions()
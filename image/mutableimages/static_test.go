@@ -142,5 +142,61 @@ var _ = Describe("static.go", func() {
 				})
 			})
 		})
+
+		Describe("`Format` method", func() {
+			Context("With a format static images can be output as", func() {
+				It("Converts the image when flushed", func() {
+					// Call method
+					Expect(mi.Format(utils.WEBP_MIME)).To(Succeed())
+
+					// Verify image was not yet converted
+					Expect(mi.Img().ImageType).To(Equal(utils.JPEG_MIME))
+
+					// Verify image was converted
+					Expect(mi.Flush()).To(Succeed())
+					Expect(mi.Img().ImageType).To(Equal(utils.WEBP_MIME))
+				})
+			})
+
+			Context("With a format static images can't be output as", func() {
+				It("Returns an error", func() {
+					// Verify return value
+					Expect(mi.Format(utils.GIF_MIME)).To(HaveOccurred())
+					Expect(mi.outputType).To(BeEmpty())
+				})
+			})
+		})
+
+		Describe("`Frame` method", func() {
+			Context("With the first frame", func() {
+				It("Returns the image", func() {
+					// Call method
+					frame, err := mi.Frame(0)
+
+					// Verify return value
+					Expect(err).To(Not(HaveOccurred()))
+					Expect(frame).To(Equal(MutableImage(mi)))
+				})
+			})
+
+			Context("With any other frame", func() {
+				It("Returns an error", func() {
+					// Call method
+					_, err := mi.Frame(1)
+
+					// Verify return value
+					Expect(err).To(HaveOccurred())
+				})
+			})
+		})
+
+		Describe("Animation methods", func() {
+			It("Return errors", func() {
+				// Verify return values
+				Expect(mi.Loop(0)).To(HaveOccurred())
+				Expect(mi.Speed(2)).To(HaveOccurred())
+				Expect(mi.Trim(0, 0)).To(HaveOccurred())
+			})
+		})
 	})
 })
@@ -0,0 +1,63 @@
+package operations
+
+import (
+	// Internal
+	"github.com/marksost/img/image/mutableimages"
+)
+
+const (
+	// Machine-readable codes describing errors that occur while processing operations
+	ERROR_CODE_BOUNDS_EXCEEDED   = "bounds_exceeded"
+	ERROR_CODE_INVALID_OPERATION = "invalid_operation"
+	ERROR_CODE_PROCESSING_FAILED = "processing_failed"
+)
+
+type (
+	// Struct representing an error that occurred while processing an operation
+	// NOTE: `OperationError` satisfies the standard `error` interface,
+	// and can be used interchangably
+	OperationError struct {
+		code      string // A machine-readable code describing the error
+		err       error  // The underlying error
+		operation string // The name of the operation the error occurred in
+	}
+)
+
+// newOperationError wraps an error with a machine-readable code,
+// returning nil if there was no error
+// NOTE: Returns an `error` rather than an `*OperationError`, so that nil errors
+// are returned as an untyped nil
+func newOperationError(code string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &OperationError{code: code, err: err}
+}
+
+// newMutableImageOperationError wraps an error returned by a mutable image while
+// applying an operation, returning nil if there was no error
+// NOTE: Operations pass-through images don't support are reported as invalid,
+// rather than as failures of the processing tools
+func newMutableImageOperationError(err error) error {
+	if err == mutableimages.ErrPassthrough {
+		return newOperationError(ERROR_CODE_INVALID_OPERATION, err)
+	}
+
+	return newOperationError(ERROR_CODE_PROCESSING_FAILED, err)
+}
+
+// Code returns the internal `code` property of the error
+func (e *OperationError) Code() string {
+	return e.code
+}
+
+// Error returns the string of the underlying error
+func (e *OperationError) Error() string {
+	return e.err.Error()
+}
+
+// Operation returns the name of the operation the error occurred in
+func (e *OperationError) Operation() string {
+	return e.operation
+}
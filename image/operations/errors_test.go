@@ -0,0 +1,39 @@
+// Tests the errors.go file
+package operations
+
+import (
+	// Standard lib
+	"fmt"
+
+	// Internal
+	"github.com/marksost/img/image/mutableimages"
+
+	// Third-party
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("errors.go", func() {
+	Describe("`newMutableImageOperationError` method", func() {
+		It("Returns nil without an error", func() {
+			// Verify return value
+			Expect(newMutableImageOperationError(nil)).To(BeNil())
+		})
+
+		It("Reports operations pass-through images don't support as invalid", func() {
+			// Call method
+			err := newMutableImageOperationError(mutableimages.ErrPassthrough)
+
+			// Verify return value
+			Expect(err.(*OperationError).Code()).To(Equal(ERROR_CODE_INVALID_OPERATION))
+		})
+
+		It("Reports all other errors as processing failures", func() {
+			// Call method
+			err := newMutableImageOperationError(fmt.Errorf("foo"))
+
+			// Verify return value
+			Expect(err.(*OperationError).Code()).To(Equal(ERROR_CODE_PROCESSING_FAILED))
+		})
+	})
+})
@@ -41,7 +41,12 @@ func (o *CropOperation) Process(mi *mutableimages.MutableImage) error {
 	}
 
 	// Return value from crop operation
-	return o.mi.Crop(o.values)
+	return newMutableImageOperationError(o.mi.Crop(o.values))
+}
+
+// Name returns the name of this operation
+func (o *CropOperation) Name() string {
+	return OPERATION_NAME_CROP
 }
 
 // String returns a string representation of this operation
@@ -81,7 +86,7 @@ func (o *CropOperation) Validate() error {
 	// Verify operation dimensions are within image bounds
 	if o.values.X+o.values.Width > o.mi.GetWidth() ||
 		o.values.Y+o.values.Height > o.mi.GetHeight() {
-		return fmt.Errorf("Target values exceed image bounds")
+		return newOperationError(ERROR_CODE_BOUNDS_EXCEEDED, fmt.Errorf("Target values exceed image bounds"))
 	}
 
 	return nil
@@ -0,0 +1,90 @@
+package operations
+
+import (
+	// Standard lib
+	"fmt"
+	"strings"
+
+	// Internal
+	"github.com/marksost/img/image/mutableimages"
+	"github.com/marksost/img/image/utils"
+)
+
+var (
+	// Map of format names to the MIME types they output images as
+	formatMimeTypes = map[string]string{
+		"gif":  utils.GIF_MIME,
+		"jpeg": utils.JPEG_MIME,
+		"jpg":  utils.JPEG_MIME,
+		"png":  utils.PNG_MIME,
+		"webp": utils.WEBP_MIME,
+	}
+)
+
+type (
+	// Struct representing a format operation to be performed on an image,
+	// changing the format the image is output as
+	FormatOperation struct {
+		// Mutable image to use when processing this operation
+		mi mutableimages.MutableImage
+		// Raw query string value for this operation
+		rawValue string
+		// Value used when operating on the image
+		value string
+	}
+)
+
+// Process is used to perform the actual operation processing
+// on a given image
+func (o *FormatOperation) Process(mi *mutableimages.MutableImage) error {
+	// Set internal value
+	o.mi = *mi
+
+	// Parse raw value
+	if err := o.parse(); err != nil {
+		return err
+	}
+
+	// Validate operation
+	if err := o.Validate(); err != nil {
+		return err
+	}
+
+	// Return value from format operation
+	return o.mi.Format(o.value)
+}
+
+// Name returns the name of this operation
+func (o *FormatOperation) Name() string {
+	return OPERATION_NAME_FORMAT
+}
+
+// String returns a string representation of this operation
+func (o *FormatOperation) String() string {
+	// Validate operation
+	if err := o.Validate(); err != nil {
+		return ""
+	}
+
+	return OPERATION_NAME_FORMAT + QUERY_STRING_ENTRY_DELIMITER + strings.TrimPrefix(o.value, "image/")
+}
+
+// Validate returns a boolean indicating if the operation can be run,
+// including checking source image against proposed operation parameters
+func (o *FormatOperation) Validate() error {
+	// Verify value exists
+	if o.value == "" {
+		return fmt.Errorf("Invalid format: %s", o.rawValue)
+	}
+
+	return nil
+}
+
+// parse is used to parse an operation's raw value and convert it
+// into usable data for the operation
+func (o *FormatOperation) parse() error {
+	// Convert format name to MIME type
+	o.value = formatMimeTypes[o.rawValue]
+
+	return nil
+}
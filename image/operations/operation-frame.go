@@ -0,0 +1,118 @@
+package operations
+
+import (
+	// Standard lib
+	"fmt"
+	"strconv"
+
+	// Internal
+	"github.com/marksost/img/helpers"
+	"github.com/marksost/img/image/mutableimages"
+)
+
+const (
+	// Named values that can be used to select a frame
+	FRAME_FIRST  = "first"
+	FRAME_LAST   = "last"
+	FRAME_MIDDLE = "middle"
+)
+
+type (
+	// Struct representing a frame operation to be performed on an image,
+	// selecting a single frame of an animated image to output as a static image
+	FrameOperation struct {
+		// Mutable image to use when processing this operation
+		mi mutableimages.MutableImage
+		// Raw query string value for this operation
+		rawValue string
+		// Value used when operating on the image
+		// NOTE: Frames are numbered from zero
+		value int
+	}
+)
+
+// Process is used to perform the actual operation processing
+// on a given image
+// NOTE: Replaces the image with a static image of the selected frame
+func (o *FrameOperation) Process(mi *mutableimages.MutableImage) error {
+	// Set internal value
+	o.mi = *mi
+
+	// Parse raw value
+	if err := o.parse(); err != nil {
+		return err
+	}
+
+	// Validate operation
+	if err := o.Validate(); err != nil {
+		return err
+	}
+
+	// Select frame
+	frame, err := o.mi.Frame(o.value)
+	if err != nil {
+		return newMutableImageOperationError(err)
+	}
+
+	// Replace image with frame
+	*mi = frame
+
+	return nil
+}
+
+// Name returns the name of this operation
+func (o *FrameOperation) Name() string {
+	return OPERATION_NAME_FRAME
+}
+
+// String returns a string representation of this operation
+func (o *FrameOperation) String() string {
+	// Validate operation
+	if err := o.Validate(); err != nil {
+		return ""
+	}
+
+	return OPERATION_NAME_FRAME + QUERY_STRING_ENTRY_DELIMITER + helpers.Int2String(o.value)
+}
+
+// Validate returns a boolean indicating if the operation can be run,
+// including checking source image against proposed operation parameters
+func (o *FrameOperation) Validate() error {
+	// Verify image exists
+	if o.mi == nil {
+		return fmt.Errorf("Invalid values. Operation appears to not have been initialized")
+	}
+
+	// Verify frame exists
+	if o.value < 0 || o.value >= o.mi.Img().Frames {
+		return newOperationError(ERROR_CODE_BOUNDS_EXCEEDED, fmt.Errorf("Invalid frame %d. Image has %d frames", o.value, o.mi.Img().Frames))
+	}
+
+	return nil
+}
+
+// parse is used to parse an operation's raw value and convert it
+// into usable data for the operation
+func (o *FrameOperation) parse() error {
+	// Get number of frames
+	frames := o.mi.Img().Frames
+
+	switch o.rawValue {
+	case FRAME_FIRST:
+		o.value = 0
+	case FRAME_LAST:
+		o.value = frames - 1
+	case FRAME_MIDDLE:
+		o.value = frames / 2
+	default:
+		// Convert raw value to int
+		value, err := strconv.Atoi(o.rawValue)
+		if err != nil {
+			return fmt.Errorf("Invalid frame: %s", o.rawValue)
+		}
+
+		o.value = value
+	}
+
+	return nil
+}
@@ -0,0 +1,111 @@
+package operations
+
+import (
+	// Standard lib
+	"fmt"
+	"strconv"
+	"strings"
+
+	// Internal
+	"github.com/marksost/img/helpers"
+	"github.com/marksost/img/image/mutableimages"
+)
+
+const (
+	// The delimiter to be used when splitting the start and end of a range of frames
+	FRAMES_DELIMITER = "-"
+)
+
+type (
+	// Struct representing a frames operation to be performed on an image,
+	// trimming an animated image to a range of it's frames
+	FramesOperation struct {
+		// Mutable image to use when processing this operation
+		mi mutableimages.MutableImage
+		// Raw query string value for this operation
+		rawValue string
+		// Values used when operating on the image
+		// NOTE: Frames are numbered from zero, and both ends of the range are kept
+		start, end int
+	}
+)
+
+// Process is used to perform the actual operation processing
+// on a given image
+func (o *FramesOperation) Process(mi *mutableimages.MutableImage) error {
+	// Set internal value
+	o.mi = *mi
+
+	// Parse raw value
+	if err := o.parse(); err != nil {
+		return err
+	}
+
+	// Validate operation
+	if err := o.Validate(); err != nil {
+		return err
+	}
+
+	// Return value from frames operation
+	return o.mi.Trim(o.start, o.end)
+}
+
+// Name returns the name of this operation
+func (o *FramesOperation) Name() string {
+	return OPERATION_NAME_FRAMES
+}
+
+// String returns a string representation of this operation
+func (o *FramesOperation) String() string {
+	// Validate operation
+	if err := o.Validate(); err != nil {
+		return ""
+	}
+
+	return OPERATION_NAME_FRAMES + QUERY_STRING_ENTRY_DELIMITER + helpers.Int2String(o.start) + FRAMES_DELIMITER + helpers.Int2String(o.end)
+}
+
+// Validate returns a boolean indicating if the operation can be run,
+// including checking source image against proposed operation parameters
+func (o *FramesOperation) Validate() error {
+	// Verify image exists
+	if o.mi == nil {
+		return fmt.Errorf("Invalid values. Operation appears to not have been initialized")
+	}
+
+	// Verify range
+	if o.start < 0 || o.start > o.end {
+		return fmt.Errorf("Invalid frames %d-%d", o.start, o.end)
+	}
+
+	// Verify frames exist
+	if o.end >= o.mi.Img().Frames {
+		return newOperationError(ERROR_CODE_BOUNDS_EXCEEDED, fmt.Errorf("Invalid frames %d-%d. Image has %d frames", o.start, o.end, o.mi.Img().Frames))
+	}
+
+	return nil
+}
+
+// parse is used to parse an operation's raw value and convert it
+// into usable data for the operation
+func (o *FramesOperation) parse() error {
+	var (
+		// Errors to be used throughout this method
+		startErr, endErr error
+	)
+
+	// Split raw value and validate result
+	b := strings.Split(o.rawValue, FRAMES_DELIMITER)
+	if len(b) != 2 {
+		return fmt.Errorf("Invalid values passed in for operation")
+	}
+
+	// Set operation values
+	o.start, startErr = strconv.Atoi(b[0])
+	o.end, endErr = strconv.Atoi(b[1])
+	if startErr != nil || endErr != nil {
+		return fmt.Errorf("Invalid frames: %s", o.rawValue)
+	}
+
+	return nil
+}
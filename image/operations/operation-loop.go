@@ -0,0 +1,91 @@
+package operations
+
+import (
+	// Standard lib
+	"fmt"
+	"strconv"
+
+	// Internal
+	"github.com/marksost/img/helpers"
+	"github.com/marksost/img/image/mutableimages"
+)
+
+const (
+	// The max number of times an animation can be set to play
+	// NOTE: GIFs store loop counts as 16-bit integers
+	MAX_LOOP_COUNT = 65535
+)
+
+type (
+	// Struct representing a loop operation to be performed on an image,
+	// setting the number of times an animated image plays
+	LoopOperation struct {
+		// Mutable image to use when processing this operation
+		mi mutableimages.MutableImage
+		// Raw query string value for this operation
+		rawValue string
+		// Value used when operating on the image
+		// NOTE: A value of 0 plays the animation forever
+		value int
+	}
+)
+
+// Process is used to perform the actual operation processing
+// on a given image
+func (o *LoopOperation) Process(mi *mutableimages.MutableImage) error {
+	// Set internal value
+	o.mi = *mi
+
+	// Parse raw value
+	if err := o.parse(); err != nil {
+		return err
+	}
+
+	// Validate operation
+	if err := o.Validate(); err != nil {
+		return err
+	}
+
+	// Return value from loop operation
+	return o.mi.Loop(o.value)
+}
+
+// Name returns the name of this operation
+func (o *LoopOperation) Name() string {
+	return OPERATION_NAME_LOOP
+}
+
+// String returns a string representation of this operation
+func (o *LoopOperation) String() string {
+	// Validate operation
+	if err := o.Validate(); err != nil {
+		return ""
+	}
+
+	return OPERATION_NAME_LOOP + QUERY_STRING_ENTRY_DELIMITER + helpers.Int2String(o.value)
+}
+
+// Validate returns a boolean indicating if the operation can be run,
+// including checking source image against proposed operation parameters
+func (o *LoopOperation) Validate() error {
+	// Verify value is within range
+	if o.value < 0 || o.value > MAX_LOOP_COUNT {
+		return fmt.Errorf("Invalid value")
+	}
+
+	return nil
+}
+
+// parse is used to parse an operation's raw value and convert it
+// into usable data for the operation
+func (o *LoopOperation) parse() error {
+	// Convert raw value to int
+	value, err := strconv.Atoi(o.rawValue)
+	if err != nil {
+		return fmt.Errorf("Invalid loop count: %s", o.rawValue)
+	}
+
+	o.value = value
+
+	return nil
+}
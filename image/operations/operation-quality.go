@@ -40,7 +40,12 @@ func (o *QualityOperation) Process(mi *mutableimages.MutableImage) error {
 	}
 
 	// Return value from quality operation
-	return o.mi.Quality(o.value)
+	return newMutableImageOperationError(o.mi.Quality(o.value))
+}
+
+// Name returns the name of this operation
+func (o *QualityOperation) Name() string {
+	return OPERATION_NAME_QUALITY
 }
 
 // String returns a string representation of this operation
@@ -41,7 +41,12 @@ func (o *ResizeOperation) Process(mi *mutableimages.MutableImage) error {
 	}
 
 	// Return value from resize operation
-	return o.mi.Resize(o.values)
+	return newMutableImageOperationError(o.mi.Resize(o.values))
+}
+
+// Name returns the name of this operation
+func (o *ResizeOperation) Name() string {
+	return OPERATION_NAME_RESIZE
 }
 
 // String returns a string representation of this operation
@@ -77,7 +82,7 @@ func (o *ResizeOperation) Validate() error {
 
 	// Verify operation isn't trying to up-size image
 	if o.values.Width > o.mi.GetWidth() || o.values.Height > o.mi.GetHeight() {
-		return fmt.Errorf("Upsizing not supported for resize operations")
+		return newOperationError(ERROR_CODE_BOUNDS_EXCEEDED, fmt.Errorf("Upsizing not supported for resize operations"))
 	}
 
 	return nil
@@ -0,0 +1,84 @@
+package operations
+
+import (
+	// Standard lib
+	"fmt"
+
+	// Internal
+	"github.com/marksost/img/helpers"
+	"github.com/marksost/img/image/mutableimages"
+)
+
+const (
+	// The max factor animations can be sped up or slowed down by
+	MAX_SPEED_FACTOR = 10.0
+)
+
+type (
+	// Struct representing a speed operation to be performed on an image,
+	// scaling the delay between each frame of an animated image
+	SpeedOperation struct {
+		// Mutable image to use when processing this operation
+		mi mutableimages.MutableImage
+		// Raw query string value for this operation
+		rawValue string
+		// Value used when operating on the image
+		// NOTE: Values above 1 speed the animation up, values below 1 slow it down
+		value float64
+	}
+)
+
+// Process is used to perform the actual operation processing
+// on a given image
+func (o *SpeedOperation) Process(mi *mutableimages.MutableImage) error {
+	// Set internal value
+	o.mi = *mi
+
+	// Parse raw value
+	if err := o.parse(); err != nil {
+		return err
+	}
+
+	// Validate operation
+	if err := o.Validate(); err != nil {
+		return err
+	}
+
+	// Return value from speed operation
+	return o.mi.Speed(o.value)
+}
+
+// Name returns the name of this operation
+func (o *SpeedOperation) Name() string {
+	return OPERATION_NAME_SPEED
+}
+
+// String returns a string representation of this operation
+func (o *SpeedOperation) String() string {
+	// Validate operation
+	if err := o.Validate(); err != nil {
+		return ""
+	}
+
+	return OPERATION_NAME_SPEED + QUERY_STRING_ENTRY_DELIMITER + helpers.Float642String(o.value)
+}
+
+// Validate returns a boolean indicating if the operation can be run,
+// including checking source image against proposed operation parameters
+func (o *SpeedOperation) Validate() error {
+	// Verify value is within range
+	if o.value < 1/MAX_SPEED_FACTOR || o.value > MAX_SPEED_FACTOR {
+		return fmt.Errorf("Invalid value")
+	}
+
+	return nil
+}
+
+// parse is used to parse an operation's raw value and convert it
+// into usable data for the operation
+func (o *SpeedOperation) parse() error {
+	// Convert raw value to float64
+	o.value = helpers.String2Float64(o.rawValue)
+
+	return nil
+}
@@ -3,10 +3,18 @@ package operations
 import (
 	// Standard lib
 	"fmt"
+	"reflect"
 	"strings"
+	"time"
 
 	// Internal
+	"github.com/marksost/img/helpers"
 	"github.com/marksost/img/image/mutableimages"
+	"github.com/marksost/img/metrics"
+	"github.com/marksost/img/tracing"
+
+	// Third-party
+	log "github.com/Sirupsen/logrus"
 )
 
 const (
@@ -14,18 +22,48 @@ const (
 	MAX_OPERATIONS = 5
 	// The name of the crop operation
 	OPERATION_NAME_CROP = "crop"
+	// The name of the format operation
+	OPERATION_NAME_FORMAT = "format"
+	// The name of the frame operation
+	OPERATION_NAME_FRAME = "frame"
+	// The name of the frames operation
+	OPERATION_NAME_FRAMES = "frames"
+	// The name of the loop operation
+	OPERATION_NAME_LOOP = "loop"
 	// The name of the quality operation
 	OPERATION_NAME_OUTPUT_QUALITY = "output-quality"
 	// The name of the quality operation
 	OPERATION_NAME_QUALITY = "quality"
 	// The name of the resize operation
 	OPERATION_NAME_RESIZE = "resize"
+	// The name of the speed operation
+	OPERATION_NAME_SPEED = "speed"
+	// The name of the processing stage the quality operation is timed and traced as
+	OPERATION_STAGE_ENCODE = "encode"
+	// The name of the processing stage operations queued by mutable images are run and traced as
+	// NOTE: Queued operations are run as the output image is encoded, so are timed as part of encoding
+	OPERATION_STAGE_FLUSH = "flush"
 	// The delimiter to be used when splitting query strings
 	QUERY_STRING_DELIMITER = "&"
 	// The delimiter to be used when splitting query string keys and values
 	QUERY_STRING_ENTRY_DELIMITER = "="
 )
 
+var (
+	// Metrics recorded while processing operations
+	operationDuration = metrics.NewHistogram(
+		"img_operation_duration_seconds",
+		"Time taken to process image operations, by operation and mutable image implementation.",
+		metrics.DurationBuckets,
+		"operation", "implementation",
+	)
+	operationFailures = metrics.NewCounter(
+		"img_operation_failures_total",
+		"Number of image operations that failed, by operation and mutable image implementation.",
+		"operation", "implementation",
+	)
+)
+
 type (
 	// Inteface all image operations must satisfy
 	Operation interface {
@@ -34,8 +72,14 @@ type (
 		Validate() error
 
 		// Internal property methods
+		Name() string
 		String() string
 	}
+	// Struct representing how long a single operation took to process
+	OperationTiming struct {
+		Duration time.Duration // Time taken to process the operation
+		Name     string        // Name of the processing stage the operation is timed as
+	}
 	// Struct representing an orchestrator for handling all image operations
 	OperationController struct {
 		// A slice of zero or more operations to run on an image
@@ -43,8 +87,17 @@ type (
 		// A special operation that handles image quality manipulation
 		// after all other operations are run
 		QualityOperation Operation
+		// The logger to log operation results with
+		log *log.Entry
+		// A boolean indicating if the quality operation was requested,
+		// rather than set by default
+		qualityRequested bool
 		// A string representing the raw query string from the request
 		queryString string
+		// The span operations are traced as children of
+		span *tracing.Span
+		// A slice of the time taken to process each operation, in processing order
+		timings []OperationTiming
 	}
 )
 
@@ -56,10 +109,20 @@ func NewOperation(operationType, value string) (Operation, error) {
 	switch operationType {
 	case OPERATION_NAME_CROP:
 		op = &CropOperation{rawValue: value}
+	case OPERATION_NAME_FORMAT:
+		op = &FormatOperation{rawValue: value}
+	case OPERATION_NAME_FRAME:
+		op = &FrameOperation{rawValue: value}
+	case OPERATION_NAME_FRAMES:
+		op = &FramesOperation{rawValue: value}
+	case OPERATION_NAME_LOOP:
+		op = &LoopOperation{rawValue: value}
 	case OPERATION_NAME_OUTPUT_QUALITY, OPERATION_NAME_QUALITY:
 		op = &QualityOperation{rawValue: value}
 	case OPERATION_NAME_RESIZE:
 		op = &ResizeOperation{rawValue: value}
+	case OPERATION_NAME_SPEED:
+		op = &SpeedOperation{rawValue: value}
 	default:
 		return nil, fmt.Errorf("Unsupported operation type: %s", operationType)
 	}
@@ -74,6 +137,7 @@ func NewOperationController(qs []byte) *OperationController {
 		Operations: make([]Operation, 0, MAX_OPERATIONS),
 		// Set default quality operation
 		QualityOperation: &QualityOperation{rawValue: "0"},
+		log:              log.NewEntry(log.StandardLogger()),
 		// NOTE: String conversion here may cause weirdness with non-UTF-8 chars
 		queryString: string(qs),
 	}
@@ -91,17 +155,51 @@ func NewOperationController(qs []byte) *OperationController {
 func (oc *OperationController) Process(mi *mutableimages.MutableImage) error {
 	// Loop through registered operations
 	for _, op := range oc.Operations {
-		if err := op.Process(mi); err != nil {
+		if err := oc.processOperation(op, mi); err != nil {
 			return err
 		}
 	}
 
 	// Process quality if needed
 	if oc.QualityOperation != nil {
-		oc.QualityOperation.Process(mi)
+		oc.processOperation(oc.QualityOperation, mi)
 	}
 
-	return nil
+	// Run any operations the mutable image queued
+	return oc.flush(mi)
+}
+
+// Names returns the names of all operations requested,
+// including the quality operation when requested
+func (oc *OperationController) Names() []string {
+	names := make([]string, 0, len(oc.Operations)+1)
+
+	// Loop through registered operations
+	for _, op := range oc.Operations {
+		names = append(names, op.Name())
+	}
+
+	// Add quality operation if needed
+	if oc.qualityRequested {
+		names = append(names, oc.QualityOperation.Name())
+	}
+
+	return names
+}
+
+// Timings returns the time taken to process each operation, in processing order
+func (oc *OperationController) Timings() []OperationTiming {
+	return oc.timings
+}
+
+// SetLogger sets the logger used to log operation results
+func (oc *OperationController) SetLogger(entry *log.Entry) {
+	oc.log = entry
+}
+
+// SetSpan sets the span operations are traced as children of
+func (oc *OperationController) SetSpan(span *tracing.Span) {
+	oc.span = span
 }
 
 // filterParams takes a raw query string from a request, splits it up
@@ -143,9 +241,105 @@ func (oc *OperationController) filterParams() {
 		// meaning multiple quality query string arguments will override each other
 		if bits[0] == OPERATION_NAME_OUTPUT_QUALITY || bits[0] == OPERATION_NAME_QUALITY {
 			oc.QualityOperation = operation
+			oc.qualityRequested = true
+		} else if bits[0] == OPERATION_NAME_FRAME {
+			// Prepend frame operation to operations slice
+			// NOTE: Frames are selected before any other operations are run,
+			// so that they're only run on a single frame
+			oc.Operations = append([]Operation{operation}, oc.Operations...)
 		} else {
 			// Append new operation to operations slice
-			oc.Operations = append(oc.Operations, operation)			
+			oc.Operations = append(oc.Operations, operation)
 		}
 	}
 }
+
+// flush runs any operations a mutable image queued while processing operations,
+// tracing them as a single processing stage and timing them as part of encoding
+func (oc *OperationController) flush(mi *mutableimages.MutableImage) error {
+	// Trace flush
+	span := tracing.StartSpan(oc.span, OPERATION_STAGE_FLUSH, tracing.SPAN_KIND_INTERNAL)
+	defer span.End()
+
+	// Flush mutable image, recording timing
+	start := time.Now()
+	err := (*mi).Flush()
+	oc.addEncodeTiming(time.Since(start))
+	span.SetError(err)
+
+	if err != nil {
+		oc.log.WithError(err).Debug("Failed to flush queued operations")
+		return &OperationError{code: ERROR_CODE_PROCESSING_FAILED, err: err}
+	}
+
+	return nil
+}
+
+// addEncodeTiming adds time taken encoding the output image to the
+// encoding timing, recording a new timing if none exists
+func (oc *OperationController) addEncodeTiming(duration time.Duration) {
+	// Loop through timings, from most recent, looking for encoding
+	for n := len(oc.timings) - 1; n >= 0; n-- {
+		if oc.timings[n].Name == OPERATION_STAGE_ENCODE {
+			oc.timings[n].Duration += duration
+			return
+		}
+	}
+
+	oc.timings = append(oc.timings, OperationTiming{Duration: duration, Name: OPERATION_STAGE_ENCODE})
+}
+
+// processOperation processes a single operation on a mutable image,
+// recording metrics for it along the way
+func (oc *OperationController) processOperation(op Operation, mi *mutableimages.MutableImage) error {
+	// Store the mutable image implementation the operation is processed with
+	// NOTE: Stored before processing, since operations may replace the image
+	implementation := reflect.Indirect(reflect.ValueOf(*mi)).Type().Name()
+
+	// Trace operation
+	// NOTE: The quality operation encodes the output image, so is timed and traced as such
+	stage, name := op.Name(), "operation "+op.Name()
+	if op == oc.QualityOperation {
+		stage, name = OPERATION_STAGE_ENCODE, OPERATION_STAGE_ENCODE
+	}
+
+	span := tracing.StartSpan(oc.span, name, tracing.SPAN_KIND_INTERNAL)
+	span.SetAttribute("implementation", implementation)
+	span.SetAttribute("operation", op.Name())
+	defer span.End()
+
+	// Process operation
+	start := time.Now()
+	err := op.Process(mi)
+	span.SetError(err)
+
+	// Record metrics and timing
+	duration := time.Since(start)
+	operationDuration.Observe(duration.Seconds(), op.Name(), implementation)
+	oc.timings = append(oc.timings, OperationTiming{Duration: duration, Name: stage})
+
+	// Log result
+	entry := oc.log.WithFields(log.Fields{
+		"duration-ms":    helpers.Float642String(duration.Seconds() * 1000),
+		"implementation": implementation,
+		"operation":      op.Name(),
+	})
+
+	if err == nil {
+		entry.Debug("Processed operation")
+		return nil
+	}
+
+	entry.WithError(err).Debug("Failed to process operation")
+	operationFailures.Inc(op.Name(), implementation)
+
+	// Attach the operation to the error, treating unclassified errors as invalid operations
+	oe, ok := err.(*OperationError)
+	if !ok {
+		oe = &OperationError{code: ERROR_CODE_INVALID_OPERATION, err: err}
+	}
+
+	oe.operation = op.Name()
+
+	return oe
+}
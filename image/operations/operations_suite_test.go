@@ -74,6 +74,9 @@ func (o *MockOperationWithError) Process(mi *mutableimages.MutableImage) error {
 // Mock operations's Validate method
 func (o *MockOperationWithError) Validate() error { return nil }
 
+// Mock operations's Name method
+func (o *MockOperationWithError) Name() string { return "mock-operation-with-error" }
+
 // Mock operations's String method
 func (o *MockOperationWithError) String() string { return "mock-operation-with-error" }
 
@@ -83,6 +86,9 @@ func (o *MockOperationWithoutError) Process(mi *mutableimages.MutableImage) erro
 // Mock operations's Validate method
 func (o *MockOperationWithoutError) Validate() error { return nil }
 
+// Mock operations's Name method
+func (o *MockOperationWithoutError) Name() string { return "mock-operation-without-error" }
+
 // Mock operations's String method
 func (o *MockOperationWithoutError) String() string { return "mock-operation-without-error" }
 
@@ -108,6 +108,17 @@ var _ = Describe("operations.go", func() {
 					// Verify return value
 					Expect(err).To(HaveOccurred())
 				})
+
+				It("Classifies the error and attaches the operation's name", func() {
+					// Call method
+					err := oc.Process(&mi)
+
+					// Verify return value
+					Expect(err).To(BeAssignableToTypeOf(&OperationError{}))
+					Expect(err.(*OperationError).Code()).To(Equal(ERROR_CODE_INVALID_OPERATION))
+					Expect(err.(*OperationError).Operation()).To(Equal("mock-operation-with-error"))
+					Expect(err.Error()).To(Equal("Error"))
+				})
 			})
 
 			Context("With an operation that returns an error", func() {
@@ -127,6 +138,97 @@ var _ = Describe("operations.go", func() {
 				})
 			})
 		})
+
+		Context("With a frame and format requested from an animated image", func() {
+			BeforeEach(func() {
+				// Create GIF mutable image
+				data, err = ioutil.ReadFile(path.Join("../../test/images/1x1.gif"))
+				if err != nil {
+					panic("Error reading image. Tests cannot continue. " + err.Error())
+				}
+
+				mi, err = mutableimages.NewMutableImage(data, utils.GIF_MIME)
+				if err != nil {
+					panic("Error creating GIF mutable image. Tests cannot continue. " + err.Error())
+				}
+
+				oc = NewOperationController([]byte("format=png&frame=last"))
+			})
+
+			It("Replaces the image with a static image of the frame", func() {
+				// Call method
+				Expect(oc.Process(&mi)).To(Succeed())
+
+				// Verify image was replaced
+				Expect(mi.Img().Animated).To(BeFalse())
+				Expect(mi.Img().ImageType).To(Equal(utils.PNG_MIME))
+				Expect(oc.Operations[0].String()).To(Equal("frame=0"))
+				Expect(oc.Operations[1].String()).To(Equal("format=png"))
+			})
+		})
+
+		Context("With an animation operation requested from a static image", func() {
+			BeforeEach(func() {
+				// Reset operation controller
+				oc = NewOperationController([]byte("speed=2"))
+			})
+
+			It("Returns an invalid operation error", func() {
+				// Call method
+				err := oc.Process(&mi)
+
+				// Verify return value
+				Expect(err).To(HaveOccurred())
+				Expect(err.(*OperationError).Code()).To(Equal(ERROR_CODE_INVALID_OPERATION))
+				Expect(err.(*OperationError).Operation()).To(Equal(OPERATION_NAME_SPEED))
+			})
+		})
+
+		Describe("`Timings` method", func() {
+			BeforeEach(func() {
+				// Set operations
+				oc.Operations = []Operation{
+					&MockOperationWithoutError{},
+				}
+			})
+
+			It("Returns the time taken to process each operation, including queued operations in encoding", func() {
+				// Call method
+				Expect(oc.Process(&mi)).To(Succeed())
+
+				// Verify return value
+				timings := oc.Timings()
+				Expect(timings).To(HaveLen(2))
+				Expect(timings[0].Name).To(Equal("mock-operation-without-error"))
+				Expect(timings[1].Name).To(Equal(OPERATION_STAGE_ENCODE))
+			})
+		})
+
+		Describe("`Names` method", func() {
+			Context("Without a requested quality operation", func() {
+				BeforeEach(func() {
+					// Create operation controller
+					oc = NewOperationController([]byte("resize=10,10&crop=5,5"))
+				})
+
+				It("Returns the names of the requested operations", func() {
+					// Verify return value
+					Expect(oc.Names()).To(Equal([]string{OPERATION_NAME_RESIZE, OPERATION_NAME_CROP}))
+				})
+			})
+
+			Context("With a requested quality operation", func() {
+				BeforeEach(func() {
+					// Create operation controller
+					oc = NewOperationController([]byte("output-quality=50&resize=10,10"))
+				})
+
+				It("Includes the quality operation's name", func() {
+					// Verify return value
+					Expect(oc.Names()).To(Equal([]string{OPERATION_NAME_RESIZE, OPERATION_NAME_QUALITY}))
+				})
+			})
+		})
 	})
 
 	Describe("OperationController utility methods", func() {
@@ -148,6 +250,21 @@ var _ = Describe("operations.go", func() {
 				// Verify length of operations
 				Expect(len(oc.Operations)).To(Equal(MAX_OPERATIONS))
 			})
+
+			Context("With a frame operation", func() {
+				BeforeEach(func() {
+					// Set query string
+					oc.queryString = "resize=1x1&format=png&frame=first"
+				})
+
+				It("Runs the frame operation before all other operations", func() {
+					// Call method
+					oc.filterParams()
+
+					// Verify order of operations
+					Expect(oc.Names()).To(Equal([]string{OPERATION_NAME_FRAME, OPERATION_NAME_RESIZE, OPERATION_NAME_FORMAT}))
+				})
+			})
 		})
 	})
 })
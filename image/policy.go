@@ -0,0 +1,92 @@
+// policy contains all functionality around enforcing the restrictions
+// of the API key an image was requested with
+package image
+
+import (
+	// Standard lib
+	"fmt"
+	"net/http"
+	"strings"
+
+	// Internal
+	"github.com/marksost/img/config"
+)
+
+const (
+	// Key the policy of a request's API key is stored under in the request context
+	POLICY_CONTEXT_KEY = "policy"
+)
+
+// authorizeRequest verifies the source and operations of the image are allowed
+// by the request's policy, before any downloading or processing is done
+func (i *Image) authorizeRequest() error {
+	// Get policy, allowing all requests without one
+	policy := i.policy()
+	if policy == nil {
+		return nil
+	}
+
+	// Verify source is allowed
+	source := i.source()
+	if len(policy.Sources) > 0 && !containsFold(policy.Sources, source) {
+		return NewError(http.StatusForbidden, fmt.Sprintf("Source is not allowed: %s", source)).
+			WithErrorCode(ERROR_CODE_FORBIDDEN)
+	}
+
+	// Verify operations are allowed
+	if len(policy.Operations) > 0 {
+		for _, name := range i.utils.OperationController.Names() {
+			if !containsFold(policy.Operations, name) {
+				return NewError(http.StatusForbidden, fmt.Sprintf("Operation is not allowed: %s", name)).
+					WithErrorCode(ERROR_CODE_FORBIDDEN).
+					WithParam(name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// authorizeOutput verifies the dimensions of the processed image
+// are within the request's policy
+func (i *Image) authorizeOutput() error {
+	// Get policy, allowing all requests without one
+	policy := i.policy()
+	if policy == nil {
+		return nil
+	}
+
+	// Store output dimensions
+	width, height := i.utils.MutableImage.GetWidth(), i.utils.MutableImage.GetHeight()
+
+	// Verify dimensions are within limits
+	if (policy.MaxWidth > 0 && width > int64(policy.MaxWidth)) ||
+		(policy.MaxHeight > 0 && height > int64(policy.MaxHeight)) {
+		return NewError(http.StatusForbidden, fmt.Sprintf(
+			"Output dimensions %dx%d exceed the allowed maximum of %dx%d",
+			width, height, policy.MaxWidth, policy.MaxHeight,
+		)).WithErrorCode(ERROR_CODE_BOUNDS_EXCEEDED)
+	}
+
+	return nil
+}
+
+// policy returns the policy of the API key the image was requested with,
+// or nil if the request wasn't authenticated
+func (i *Image) policy() *config.KeyPolicy {
+	policy, _ := i.ctx.Get(POLICY_CONTEXT_KEY).(*config.KeyPolicy)
+
+	return policy
+}
+
+// containsFold returns a boolean indicating if a slice contains a string,
+// ignoring case
+func containsFold(slice []string, str string) bool {
+	for _, s := range slice {
+		if strings.EqualFold(s, str) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,165 @@
+// Tests the policy.go file
+package image
+
+import (
+	// Standard lib
+	"bytes"
+	goimage "image"
+	"image/color/palette"
+	"image/gif"
+	"net/http"
+
+	// Internal
+	"github.com/marksost/img/config"
+	"github.com/marksost/img/image/mutableimages"
+	"github.com/marksost/img/image/operations"
+	"github.com/marksost/img/image/utils"
+
+	// Third-party
+	"github.com/kataras/iris"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/valyala/fasthttp"
+)
+
+var _ = Describe("policy.go", func() {
+	var (
+		// Mock iris context to use within tests
+		ctx *iris.Context
+		// Mock image to test
+		i *Image
+		// Mock policy to use within tests
+		policy *config.KeyPolicy
+	)
+
+	BeforeEach(func() {
+		// Initalize config instance
+		config.Init()
+
+		// Create mock context
+		ctx = &iris.Context{
+			RequestCtx: &fasthttp.RequestCtx{},
+		}
+
+		// Create mock image
+		i = NewImage(ctx)
+		i.utils = &ImageUtils{
+			Downloader:          utils.NewDownloader("/foo-url.com/path/to/image.gif"),
+			OperationController: operations.NewOperationController([]byte("resize=5,5&quality=50")),
+		}
+
+		// Create mock policy
+		policy = &config.KeyPolicy{}
+	})
+
+	Describe("`authorizeRequest` method", func() {
+		Context("Without a policy", func() {
+			It("Returns nil", func() {
+				// Verify return value
+				Expect(i.authorizeRequest()).To(BeNil())
+			})
+		})
+
+		Context("With a policy", func() {
+			BeforeEach(func() {
+				// Store policy
+				ctx.Set(POLICY_CONTEXT_KEY, policy)
+			})
+
+			Context("That allows the source and operations", func() {
+				BeforeEach(func() {
+					// Set allowed sources and operations
+					policy.Sources = []string{"FOO-URL.com"}
+					policy.Operations = []string{"quality", "resize"}
+				})
+
+				It("Returns nil", func() {
+					// Verify return value
+					Expect(i.authorizeRequest()).To(BeNil())
+				})
+			})
+
+			Context("That doesn't allow the source", func() {
+				BeforeEach(func() {
+					// Set allowed sources
+					policy.Sources = []string{"bar-url.com"}
+				})
+
+				It("Returns a forbidden error", func() {
+					// Call method
+					err := i.authorizeRequest()
+
+					// Verify return value
+					Expect(err).To(HaveOccurred())
+					Expect(err.(*ImageRequestError).Code()).To(Equal(http.StatusForbidden))
+				})
+			})
+
+			Context("That doesn't allow an operation", func() {
+				BeforeEach(func() {
+					// Set allowed operations
+					policy.Operations = []string{"resize"}
+				})
+
+				It("Returns a forbidden error", func() {
+					// Call method
+					err := i.authorizeRequest()
+
+					// Verify return value
+					Expect(err).To(HaveOccurred())
+					Expect(err.(*ImageRequestError).Code()).To(Equal(http.StatusForbidden))
+					Expect(err.Error()).To(ContainSubstring("quality"))
+				})
+			})
+		})
+	})
+
+	Describe("`authorizeOutput` method", func() {
+		BeforeEach(func() {
+			// Generate a 10x10 GIF
+			buf := &bytes.Buffer{}
+			if err := gif.Encode(buf, goimage.NewPaletted(goimage.Rect(0, 0, 10, 10), palette.Plan9), nil); err != nil {
+				panic("Error encoding image. Tests cannot continue. " + err.Error())
+			}
+
+			// Create mutable image
+			mi, err := mutableimages.NewMutableImage(buf.Bytes(), utils.GIF_MIME)
+			if err != nil {
+				panic("Error creating mutable image. Tests cannot continue. " + err.Error())
+			}
+
+			i.utils.MutableImage = mi
+
+			// Store policy
+			ctx.Set(POLICY_CONTEXT_KEY, policy)
+		})
+
+		Context("With output dimensions within the policy's limits", func() {
+			BeforeEach(func() {
+				// Set max dimensions
+				policy.MaxWidth, policy.MaxHeight = 10, 0
+			})
+
+			It("Returns nil", func() {
+				// Verify return value
+				Expect(i.authorizeOutput()).To(BeNil())
+			})
+		})
+
+		Context("With output dimensions exceeding the policy's limits", func() {
+			BeforeEach(func() {
+				// Set max dimensions
+				policy.MaxWidth, policy.MaxHeight = 0, 5
+			})
+
+			It("Returns a forbidden error", func() {
+				// Call method
+				err := i.authorizeOutput()
+
+				// Verify return value
+				Expect(err).To(HaveOccurred())
+				Expect(err.(*ImageRequestError).Code()).To(Equal(http.StatusForbidden))
+			})
+		})
+	})
+})
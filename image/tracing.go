@@ -0,0 +1,29 @@
+// tracing contains all functionality around the per-request tracing span,
+// which the stages of processing an image are traced as children of
+package image
+
+import (
+	// Internal
+	"github.com/marksost/img/tracing"
+
+	// Third-party
+	"github.com/kataras/iris"
+)
+
+const (
+	// Key the request's span is stored under in the request context
+	SPAN_CONTEXT_KEY = "span"
+)
+
+// RequestSpan returns the span for a request, or nil if the request isn't traced
+func RequestSpan(c *iris.Context) *tracing.Span {
+	span, _ := c.Get(SPAN_CONTEXT_KEY).(*tracing.Span)
+
+	return span
+}
+
+// StartSpan starts a new span as a child of a request's span,
+// returning nil if the request isn't traced
+func StartSpan(c *iris.Context, name string) *tracing.Span {
+	return tracing.StartSpan(RequestSpan(c), name, tracing.SPAN_KIND_INTERNAL)
+}
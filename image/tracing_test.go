@@ -0,0 +1,70 @@
+// Tests the tracing.go file
+package image
+
+import (
+	// Standard lib
+	"os"
+
+	// Internal
+	"github.com/marksost/img/tracing"
+
+	// Third-party
+	"github.com/kataras/iris"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/valyala/fasthttp"
+)
+
+var _ = Describe("tracing.go", func() {
+	var (
+		// Mock iris context to use within tests
+		ctx *iris.Context
+	)
+
+	BeforeEach(func() {
+		// Create mock context
+		ctx = &iris.Context{
+			RequestCtx: &fasthttp.RequestCtx{},
+		}
+
+		// Enable tracing
+		tracing.SetExporter(tracing.NewFileExporter(os.DevNull))
+	})
+
+	AfterEach(func() {
+		// Disable tracing
+		tracing.SetExporter(nil)
+	})
+
+	Describe("`RequestSpan` and `StartSpan` methods", func() {
+		Context("Without a span set", func() {
+			It("Returns nil", func() {
+				// Verify return values
+				Expect(RequestSpan(ctx)).To(BeNil())
+				Expect(StartSpan(ctx, "decode")).To(BeNil())
+			})
+		})
+
+		Context("With a span set", func() {
+			var (
+				// Mock span to use within tests
+				span *tracing.Span
+			)
+
+			BeforeEach(func() {
+				// Set span
+				span = tracing.StartRootSpan("request", "")
+				ctx.Set(SPAN_CONTEXT_KEY, span)
+			})
+
+			It("Returns the span, or a child of it", func() {
+				// Verify return values
+				Expect(RequestSpan(ctx)).To(Equal(span))
+
+				child := StartSpan(ctx, "decode")
+				Expect(child).To(Not(BeNil()))
+				Expect(child.TraceID()).To(Equal(span.TraceID()))
+			})
+		})
+	})
+})
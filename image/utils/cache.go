@@ -0,0 +1,299 @@
+// cache encapsulates all functionality around caching downloaded source images
+// so that they can be reused, and revalidated against their origin, across requests
+package utils
+
+import (
+	// Standard lib
+	"container/list"
+	"strings"
+	"sync"
+	"time"
+
+	// Internal
+	"github.com/marksost/img/config"
+	"github.com/marksost/img/metrics"
+)
+
+const (
+	// Cache statuses describing how a downloaded image was retrieved
+	CACHE_STATUS_BYPASS      = "bypass"      // Caching is disabled
+	CACHE_STATUS_HIT         = "hit"         // A fresh cached image was used
+	CACHE_STATUS_MISS        = "miss"        // The image was downloaded from the origin
+	CACHE_STATUS_REVALIDATED = "revalidated" // A stale cached image was revalidated and reused
+)
+
+var (
+	// Cache of downloaded source images shared by all downloaders
+	// NOTE: Will be nil when source caching is disabled
+	sourceCache *SourceCache
+
+	// Metrics recorded for the source cache
+	sourceCacheRequests = metrics.NewCounter(
+		"img_source_cache_requests_total",
+		"Number of source image requests, by cache status.",
+		"status",
+	)
+	_ = metrics.NewGaugeFunc(
+		"img_source_cache_hit_ratio",
+		"Ratio of source image requests served from the cache, with or without revalidation.",
+		sourceCacheHitRatio,
+	)
+)
+
+type (
+	// Struct representing a single cached source image
+	CacheEntry struct {
+		Data         []byte    // The raw data from the downloaded image
+		ETag         string    // The ETag header returned by the origin, if any
+		LastModified string    // The Last-Modified header returned by the origin, if any
+		MimeType     string    // The detected MIME type of the downloaded image
+		StoredAt     time.Time // The time the entry was stored or last revalidated
+		key          string    // The key the entry is stored under
+	}
+	// Struct representing a point-in-time snapshot of a cache's usage
+	CacheStats struct {
+		Entries       int   `json:"entries"`       // The number of entries in the cache
+		Hits          int64 `json:"hits"`          // The number of requests served by fresh entries
+		MaxEntries    int   `json:"max-entries"`   // The max number of entries allowed in the cache
+		MaxSize       int64 `json:"max-size"`      // The max total size (in bytes) of all entry data
+		Misses        int64 `json:"misses"`        // The number of requests downloaded from the origin
+		Revalidations int64 `json:"revalidations"` // The number of requests served by revalidated entries
+		Size          int64 `json:"size"`          // The current total size (in bytes) of all entry data
+	}
+	// Struct representing an in-memory, size-bounded LRU cache of source images
+	SourceCache struct {
+		entries    map[string]*list.Element // Map of keys to their position in the LRU list
+		lru        *list.List               // List of entries, ordered from most to least recently used
+		maxEntries int                      // The max number of entries allowed in the cache
+		maxSize    int64                    // The max total size (in bytes) of all entry data
+		mutex      sync.Mutex               // Mutex used to synchronize access to the cache
+		size       int64                    // The current total size (in bytes) of all entry data
+		stats      CacheStats               // Running totals of cache usage
+		ttl        time.Duration            // The duration an entry is considered fresh for
+	}
+)
+
+// NewSourceCache creates a new `SourceCache` and returns it
+// NOTE: Zero values for `maxEntries` or `maxSize` indicate no limit
+func NewSourceCache(ttl time.Duration, maxEntries int, maxSize int64) *SourceCache {
+	return &SourceCache{
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+		maxEntries: maxEntries,
+		maxSize:    maxSize,
+		ttl:        ttl,
+	}
+}
+
+/* Begin main public functionality methods */
+
+// Delete removes the entry stored under a key, returning a boolean
+// indicating if an entry was removed
+func (sc *SourceCache) Delete(key string) bool {
+	sc.mutex.Lock()
+	defer sc.mutex.Unlock()
+
+	// Check for entry
+	el, ok := sc.entries[key]
+	if !ok {
+		return false
+	}
+
+	// Remove entry
+	sc.remove(el)
+
+	return true
+}
+
+// DeletePrefix removes all entries whose keys start with a prefix,
+// returning the number of entries removed
+func (sc *SourceCache) DeletePrefix(prefix string) int {
+	sc.mutex.Lock()
+	defer sc.mutex.Unlock()
+
+	// Loop through entries, removing matches
+	removed := 0
+	for key, el := range sc.entries {
+		if strings.HasPrefix(key, prefix) {
+			sc.remove(el)
+			removed++
+		}
+	}
+
+	return removed
+}
+
+// Get returns a copy of the entry stored under a key, or nil if none exists
+// NOTE: Marks the entry as the most recently used
+func (sc *SourceCache) Get(key string) *CacheEntry {
+	sc.mutex.Lock()
+	defer sc.mutex.Unlock()
+
+	// Check for entry
+	el, ok := sc.entries[key]
+	if !ok {
+		return nil
+	}
+
+	// Mark entry as most recently used
+	sc.lru.MoveToFront(el)
+
+	// Return a copy so callers can't modify cached state
+	entry := *el.Value.(*CacheEntry)
+
+	return &entry
+}
+
+// IsFresh returns a boolean indicating if an entry can be used
+// without revalidating it against the origin
+func (sc *SourceCache) IsFresh(entry *CacheEntry) bool {
+	return time.Since(entry.StoredAt) < sc.ttl
+}
+
+// Record adds a cache status to the cache's running usage totals
+func (sc *SourceCache) Record(status string) {
+	sc.mutex.Lock()
+	defer sc.mutex.Unlock()
+
+	// Record metric
+	sourceCacheRequests.Inc(status)
+
+	switch status {
+	case CACHE_STATUS_HIT:
+		sc.stats.Hits++
+	case CACHE_STATUS_MISS:
+		sc.stats.Misses++
+	case CACHE_STATUS_REVALIDATED:
+		sc.stats.Revalidations++
+	}
+}
+
+// Refresh marks the entry stored under a key as having just been revalidated
+func (sc *SourceCache) Refresh(key string) {
+	sc.mutex.Lock()
+	defer sc.mutex.Unlock()
+
+	// Update stored time if the entry still exists
+	if el, ok := sc.entries[key]; ok {
+		el.Value.(*CacheEntry).StoredAt = time.Now()
+		sc.lru.MoveToFront(el)
+	}
+}
+
+// Set stores an entry under a key, evicting least recently used entries as needed
+func (sc *SourceCache) Set(key string, entry *CacheEntry) {
+	sc.mutex.Lock()
+	defer sc.mutex.Unlock()
+
+	// Skip entries that could never fit
+	if sc.maxSize > 0 && int64(len(entry.Data)) > sc.maxSize {
+		return
+	}
+
+	// Remove any existing entry
+	if el, ok := sc.entries[key]; ok {
+		sc.remove(el)
+	}
+
+	// Store a copy of the entry
+	stored := *entry
+	stored.key = key
+	if stored.StoredAt.IsZero() {
+		stored.StoredAt = time.Now()
+	}
+
+	sc.entries[key] = sc.lru.PushFront(&stored)
+	sc.size += int64(len(stored.Data))
+
+	// Evict entries until within limits
+	for (sc.maxEntries > 0 && sc.lru.Len() > sc.maxEntries) ||
+		(sc.maxSize > 0 && sc.size > sc.maxSize) {
+		sc.remove(sc.lru.Back())
+	}
+}
+
+/* End main public functionality methods */
+
+/* Begin internal property methods */
+
+// Len returns the number of entries in the cache
+func (sc *SourceCache) Len() int {
+	sc.mutex.Lock()
+	defer sc.mutex.Unlock()
+
+	return sc.lru.Len()
+}
+
+// Stats returns a snapshot of the cache's current usage
+func (sc *SourceCache) Stats() CacheStats {
+	sc.mutex.Lock()
+	defer sc.mutex.Unlock()
+
+	// Copy running totals and add current state
+	stats := sc.stats
+	stats.Entries = sc.lru.Len()
+	stats.MaxEntries = sc.maxEntries
+	stats.MaxSize = sc.maxSize
+	stats.Size = sc.size
+
+	return stats
+}
+
+/* End internal property methods */
+
+/* Begin utility methods */
+
+// remove removes a single entry from the cache
+// NOTE: Expects the cache's mutex to be held by the caller
+func (sc *SourceCache) remove(el *list.Element) {
+	entry := sc.lru.Remove(el).(*CacheEntry)
+
+	delete(sc.entries, entry.key)
+	sc.size -= int64(len(entry.Data))
+}
+
+/* End utility methods */
+
+// InitSourceCache creates the source image cache shared by all downloaders
+// based on configuration values
+func InitSourceCache() {
+	// Get configuration instance
+	c := config.GetInstance()
+
+	// Disable cache if needed
+	if !c.Cache.Sources.Enabled {
+		sourceCache = nil
+		return
+	}
+
+	// Create new cache
+	sourceCache = NewSourceCache(
+		time.Duration(c.Cache.Sources.TTL)*time.Second,
+		c.Cache.Sources.MaxEntries,
+		int64(c.Cache.Sources.MaxSize)*1024*1024,
+	)
+}
+
+// sourceCacheHitRatio returns the ratio of requests served by the source cache
+// NOTE: Used to export a metric, and will return zero when caching is disabled
+func sourceCacheHitRatio() float64 {
+	// Check for disabled cache
+	if sourceCache == nil {
+		return 0
+	}
+
+	// Get stats and check for no requests
+	stats := sourceCache.Stats()
+	total := stats.Hits + stats.Revalidations + stats.Misses
+	if total == 0 {
+		return 0
+	}
+
+	return float64(stats.Hits+stats.Revalidations) / float64(total)
+}
+
+// GetSourceCache returns the initialized source image cache
+// NOTE: Will return nil when source caching is disabled
+func GetSourceCache() *SourceCache {
+	return sourceCache
+}
@@ -0,0 +1,257 @@
+// Tests the cache.go file
+package utils
+
+import (
+	// Standard lib
+	"time"
+
+	// Internal
+	"github.com/marksost/img/config"
+
+	// Third-party
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("cache.go", func() {
+	var (
+		// Mock source cache to test
+		sc *SourceCache
+	)
+
+	BeforeEach(func() {
+		// Create mock source cache
+		sc = NewSourceCache(time.Minute, 2, 10)
+	})
+
+	Describe("`NewSourceCache` method", func() {
+		It("Returns a valid, empty SourceCache instance", func() {
+			// Call method
+			sc := NewSourceCache(time.Minute, 0, 0)
+
+			// Verify return value
+			Expect(sc).To(Not(BeNil()))
+			Expect(sc.Len()).To(Equal(0))
+		})
+	})
+
+	Describe("SourceCache public functionality methods", func() {
+		Describe("`Delete` method", func() {
+			BeforeEach(func() {
+				// Store entry
+				sc.Set("foo", &CacheEntry{Data: []byte("bar")})
+			})
+
+			It("Removes an entry and returns a boolean indicating if one was removed", func() {
+				// Verify return values
+				Expect(sc.Delete("foo")).To(BeTrue())
+				Expect(sc.Delete("foo")).To(BeFalse())
+
+				// Verify entries
+				Expect(sc.Get("foo")).To(BeNil())
+				Expect(sc.size).To(BeEquivalentTo(0))
+			})
+		})
+
+		Describe("`DeletePrefix` method", func() {
+			BeforeEach(func() {
+				// Reset cache with no limits
+				sc = NewSourceCache(time.Minute, 0, 0)
+
+				// Store entries
+				sc.Set("http://foo.com/a.jpg", &CacheEntry{Data: []byte("1")})
+				sc.Set("http://foo.com/b.jpg", &CacheEntry{Data: []byte("2")})
+				sc.Set("http://bar.com/a.jpg", &CacheEntry{Data: []byte("3")})
+			})
+
+			It("Removes all entries matching the prefix and returns the number removed", func() {
+				// Call method
+				removed := sc.DeletePrefix("http://foo.com/")
+
+				// Verify return value
+				Expect(removed).To(Equal(2))
+
+				// Verify entries
+				Expect(sc.Len()).To(Equal(1))
+				Expect(sc.Get("http://bar.com/a.jpg")).To(Not(BeNil()))
+			})
+		})
+
+		Describe("`Get` method", func() {
+			Context("With no entry stored under the key", func() {
+				It("Returns nil", func() {
+					// Verify return value
+					Expect(sc.Get("foo")).To(BeNil())
+				})
+			})
+
+			Context("With an entry stored under the key", func() {
+				BeforeEach(func() {
+					// Store entry
+					sc.Set("foo", &CacheEntry{Data: []byte("bar"), ETag: "baz"})
+				})
+
+				It("Returns a copy of the entry", func() {
+					// Call method
+					entry := sc.Get("foo")
+
+					// Verify return value
+					Expect(string(entry.Data)).To(Equal("bar"))
+					Expect(entry.ETag).To(Equal("baz"))
+
+					// Verify changes to the copy aren't stored
+					entry.ETag = "test"
+					Expect(sc.Get("foo").ETag).To(Equal("baz"))
+				})
+			})
+		})
+
+		Describe("`IsFresh` method", func() {
+			It("Returns a boolean indicating if an entry is within it's TTL", func() {
+				// Verify return values
+				Expect(sc.IsFresh(&CacheEntry{StoredAt: time.Now()})).To(BeTrue())
+				Expect(sc.IsFresh(&CacheEntry{StoredAt: time.Now().Add(-time.Hour)})).To(BeFalse())
+			})
+		})
+
+		Describe("`Record` method", func() {
+			It("Adds cache statuses to the running usage totals", func() {
+				// Call method
+				sc.Record(CACHE_STATUS_HIT)
+				sc.Record(CACHE_STATUS_HIT)
+				sc.Record(CACHE_STATUS_MISS)
+				sc.Record(CACHE_STATUS_REVALIDATED)
+				sc.Record(CACHE_STATUS_BYPASS)
+
+				// Verify totals
+				stats := sc.Stats()
+				Expect(stats.Hits).To(BeEquivalentTo(2))
+				Expect(stats.Misses).To(BeEquivalentTo(1))
+				Expect(stats.Revalidations).To(BeEquivalentTo(1))
+			})
+		})
+
+		Describe("`Refresh` method", func() {
+			BeforeEach(func() {
+				// Store stale entry
+				sc.Set("foo", &CacheEntry{Data: []byte("bar"), StoredAt: time.Now().Add(-time.Hour)})
+			})
+
+			It("Marks the entry as fresh", func() {
+				// Call method
+				sc.Refresh("foo")
+
+				// Verify entry is fresh
+				Expect(sc.IsFresh(sc.Get("foo"))).To(BeTrue())
+			})
+		})
+
+		Describe("`Set` method", func() {
+			Context("When the max number of entries is exceeded", func() {
+				BeforeEach(func() {
+					// Store entries
+					sc.Set("foo", &CacheEntry{Data: []byte("1")})
+					sc.Set("bar", &CacheEntry{Data: []byte("2")})
+
+					// Mark first entry as recently used
+					sc.Get("foo")
+				})
+
+				It("Evicts the least recently used entry", func() {
+					// Call method
+					sc.Set("baz", &CacheEntry{Data: []byte("3")})
+
+					// Verify entries
+					Expect(sc.Len()).To(Equal(2))
+					Expect(sc.Get("foo")).To(Not(BeNil()))
+					Expect(sc.Get("bar")).To(BeNil())
+					Expect(sc.Get("baz")).To(Not(BeNil()))
+				})
+			})
+
+			Context("When the max size is exceeded", func() {
+				BeforeEach(func() {
+					// Store entry
+					sc.Set("foo", &CacheEntry{Data: []byte("123456")})
+				})
+
+				It("Evicts entries until within the max size", func() {
+					// Call method
+					sc.Set("bar", &CacheEntry{Data: []byte("123456")})
+
+					// Verify entries
+					Expect(sc.Len()).To(Equal(1))
+					Expect(sc.Get("foo")).To(BeNil())
+					Expect(sc.size).To(BeEquivalentTo(6))
+				})
+			})
+
+			Context("When the entry is larger than the max size", func() {
+				It("Doesn't store the entry", func() {
+					// Call method
+					sc.Set("foo", &CacheEntry{Data: []byte("12345678901")})
+
+					// Verify entries
+					Expect(sc.Len()).To(Equal(0))
+				})
+			})
+		})
+	})
+
+	Describe("SourceCache internal property methods", func() {
+		Describe("`Stats` method", func() {
+			BeforeEach(func() {
+				// Store entry
+				sc.Set("foo", &CacheEntry{Data: []byte("bar")})
+			})
+
+			It("Returns a snapshot of the cache's usage", func() {
+				// Call method
+				stats := sc.Stats()
+
+				// Verify return value
+				Expect(stats.Entries).To(Equal(1))
+				Expect(stats.MaxEntries).To(Equal(2))
+				Expect(stats.MaxSize).To(BeEquivalentTo(10))
+				Expect(stats.Size).To(BeEquivalentTo(3))
+			})
+		})
+	})
+
+	Describe("`InitSourceCache` method", func() {
+		BeforeEach(func() {
+			// Initalize config instance
+			config.Init()
+		})
+
+		AfterEach(func() {
+			// Reset source cache
+			sourceCache = nil
+		})
+
+		Context("With source caching disabled", func() {
+			BeforeEach(func() {
+				// Disable source caching
+				config.GetInstance().Cache.Sources.Enabled = false
+			})
+
+			It("Doesn't create a source cache", func() {
+				// Call method
+				InitSourceCache()
+
+				// Verify source cache
+				Expect(GetSourceCache()).To(BeNil())
+			})
+		})
+
+		Context("With source caching enabled", func() {
+			It("Creates a source cache", func() {
+				// Call method
+				InitSourceCache()
+
+				// Verify source cache
+				Expect(GetSourceCache()).To(Not(BeNil()))
+			})
+		})
+	})
+})
@@ -9,21 +9,66 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
+
+	// Internal
+	"github.com/marksost/img/helpers"
+	"github.com/marksost/img/metrics"
+	"github.com/marksost/img/tracing"
+
+	// Third-party
+	log "github.com/Sirupsen/logrus"
+)
+
+const (
+	// Request header containing the ID of the request an image is downloaded for
+	HEADER_REQUEST_ID = "X-Request-ID"
+	// Request header used to conditionally revalidate a cached image by it's ETag
+	HEADER_IF_NONE_MATCH = "If-None-Match"
+	// Request header used to conditionally revalidate a cached image by it's modified time
+	HEADER_IF_MODIFIED_SINCE = "If-Modified-Since"
+)
+
+var (
+	// Metrics recorded while downloading images
+	downloadBytes = metrics.NewHistogram(
+		"img_download_bytes",
+		"Size of images downloaded from origins, in bytes.",
+		metrics.SizeBuckets,
+	)
+	downloadDuration = metrics.NewHistogram(
+		"img_download_duration_seconds",
+		"Time taken to download images from origins, by response status code.",
+		metrics.DurationBuckets,
+		"code",
+	)
 )
 
 type (
+	// Struct representing an error caused by an origin responding with an unexpected status code
+	StatusError struct {
+		StatusCode int // The status code the origin responded with
+	}
 	// Struct representing a Downloader object used for downloading resources
 	Downloader struct {
-		data     []byte   // The raw data from the downloaded image
-		mimeType string   // The detected MIME type of the downloaded image
-		url      *url.URL // The URL to download the image from
+		cache       *SourceCache  // The cache to read and store downloaded images from/in
+		cacheStatus string        // How the downloaded image was retrieved in relation to the cache
+		data        []byte        // The raw data from the downloaded image
+		log         *log.Entry    // The logger to log download results with
+		mimeType    string        // The detected MIME type of the downloaded image
+		requestID   string        // The ID of the request the image is downloaded for, forwarded to origins
+		span        *tracing.Span // The span downloads are traced as children of
+		url         *url.URL      // The URL to download the image from
 	}
 )
 
 // NewDownloader creates a new `Downloader` and returns it
 func NewDownloader(str string) *Downloader {
 	// Create downloader instance
-	d := &Downloader{}
+	d := &Downloader{
+		cache: GetSourceCache(),
+		log:   log.NewEntry(log.StandardLogger()),
+	}
 
 	// Form URL instance from string and set downloader's URL
 	d.url, _ = d.formUrl(str)
@@ -32,35 +77,44 @@ func NewDownloader(str string) *Downloader {
 	return d
 }
 
+// Error returns a string describing the unexpected status code
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("URL returned status code other than 200: %d", e.StatusCode)
+}
+
 /* Begin main public functionality methods */
 
 // Download makes an HTTP GET request for a given URL
 // and returns the resulting data when possible
+// NOTE: Fresh cached images are used without making a request, while stale ones
+// are conditionally revalidated against the origin and reused when unchanged
 func (d *Downloader) Download() error {
-	// Make HTTP GET request
-	res, err := http.Get(d.url.String())
-	if err != nil {
-		return err
-	}
+	// Download image, timing and tracing the download
+	start := time.Now()
+	span := tracing.StartSpan(d.span, "download", tracing.SPAN_KIND_CLIENT)
+	err := d.download(span)
 
-	// Close body after processing
-	defer res.Body.Close()
+	// End span
+	span.SetAttribute("bytes", len(d.data))
+	span.SetAttribute("cache-status", d.cacheStatus)
+	span.SetAttribute("url", d.url.String())
+	span.SetError(err)
+	span.End()
 
-	// Check for success
-	if res.StatusCode != http.StatusOK {
-		return fmt.Errorf("URL returned status code other than 200: %d", res.StatusCode)
-	}
+	// Log result
+	entry := d.log.WithFields(log.Fields{
+		"bytes":        len(d.data),
+		"cache-status": d.cacheStatus,
+		"duration-ms":  helpers.Float642String(time.Since(start).Seconds() * 1000),
+		"url":          d.url.String(),
+	})
 
-	// Read data from response
-	// TO-DO: Figure out how to test this...
-	data, err := ioutil.ReadAll(res.Body)
 	if err != nil {
+		entry.WithError(err).Warn("Failed to download image")
 		return err
 	}
 
-	// Set raw data and MIME type
-	d.data = data
-	d.mimeType = getMimeType(d.data)
+	entry.Debug("Downloaded image")
 
 	return nil
 }
@@ -69,6 +123,12 @@ func (d *Downloader) Download() error {
 
 /* Begin internal propery methods */
 
+// CacheStatus returns a string representing how the downloaded image
+// was retrieved in relation to the source cache
+func (d *Downloader) CacheStatus() string {
+	return d.cacheStatus
+}
+
 // Data returns a byte slice representing the raw data from the downloaded image
 func (d *Downloader) Data() []byte {
 	return d.data
@@ -85,6 +145,29 @@ func (d *Downloader) MimeType() string {
 	return d.mimeType
 }
 
+// SetData sets the raw data of the downloaded image, detecting it's MIME type
+// NOTE: Used to stand in data from elsewhere for an image that couldn't be downloaded
+func (d *Downloader) SetData(data []byte) {
+	d.data = data
+	d.mimeType = getMimeType(data)
+}
+
+// SetLogger sets the logger used to log download results
+func (d *Downloader) SetLogger(entry *log.Entry) {
+	d.log = entry
+}
+
+// SetRequestID sets the ID of the request the image is downloaded for,
+// which is forwarded to origins
+func (d *Downloader) SetRequestID(id string) {
+	d.requestID = id
+}
+
+// SetSpan sets the span downloads are traced as children of
+func (d *Downloader) SetSpan(span *tracing.Span) {
+	d.span = span
+}
+
 // Url returns a URL struct representing the parsed URL of the requested image
 func (d *Downloader) Url() *url.URL {
 	return d.url
@@ -94,6 +177,123 @@ func (d *Downloader) Url() *url.URL {
 
 /* Begin utility methods */
 
+// download performs the actual downloading of an image for `Download`,
+// propagating the download's span to the origin
+func (d *Downloader) download(span *tracing.Span) error {
+	// Form cache key
+	key := d.url.String()
+
+	// Check cache for a previously downloaded image
+	var entry *CacheEntry
+	if d.cache != nil {
+		if entry = d.cache.Get(key); entry != nil && d.cache.IsFresh(entry) {
+			d.data, d.mimeType = entry.Data, entry.MimeType
+			d.setCacheStatus(CACHE_STATUS_HIT)
+			return nil
+		}
+	}
+
+	// Form HTTP GET request
+	req, err := http.NewRequest(http.MethodGet, key, nil)
+	if err != nil {
+		return err
+	}
+
+	// Forward request ID to the origin
+	if d.requestID != "" {
+		req.Header.Set(HEADER_REQUEST_ID, d.requestID)
+	}
+
+	// Propagate trace context to the origin
+	if traceparent := span.Traceparent(); traceparent != "" {
+		req.Header.Set(tracing.TRACEPARENT_HEADER, traceparent)
+	}
+
+	// Add conditional headers for stale cache entries
+	if entry != nil {
+		if entry.ETag != "" {
+			req.Header.Set(HEADER_IF_NONE_MATCH, entry.ETag)
+		}
+
+		if entry.LastModified != "" {
+			req.Header.Set(HEADER_IF_MODIFIED_SINCE, entry.LastModified)
+		}
+	}
+
+	// Make HTTP GET request
+	start := time.Now()
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		downloadDuration.Observe(time.Since(start).Seconds(), "error")
+		return err
+	}
+
+	// Close body after processing
+	defer res.Body.Close()
+
+	// Store origin's response status
+	span.SetAttribute("http.status_code", res.StatusCode)
+
+	// Reuse cached data if the origin reports it hasn't changed
+	if entry != nil && res.StatusCode == http.StatusNotModified {
+		downloadDuration.Observe(time.Since(start).Seconds(), helpers.Int2String(res.StatusCode))
+		d.cache.Refresh(key)
+		d.data, d.mimeType = entry.Data, entry.MimeType
+		d.setCacheStatus(CACHE_STATUS_REVALIDATED)
+		return nil
+	}
+
+	// Check for success
+	if res.StatusCode != http.StatusOK {
+		downloadDuration.Observe(time.Since(start).Seconds(), helpers.Int2String(res.StatusCode))
+		return &StatusError{StatusCode: res.StatusCode}
+	}
+
+	// Read data from response
+	// TO-DO: Figure out how to test this...
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		downloadDuration.Observe(time.Since(start).Seconds(), "error")
+		return err
+	}
+
+	// Record download metrics
+	downloadDuration.Observe(time.Since(start).Seconds(), helpers.Int2String(res.StatusCode))
+	downloadBytes.Observe(float64(len(data)))
+
+	// Set raw data and MIME type
+	d.data = data
+	d.mimeType = getMimeType(d.data)
+
+	// Store image and it's validators in the cache
+	if d.cache != nil {
+		d.cache.Set(key, &CacheEntry{
+			Data:         d.data,
+			ETag:         res.Header.Get("ETag"),
+			LastModified: res.Header.Get("Last-Modified"),
+			MimeType:     d.mimeType,
+		})
+	}
+
+	// Set cache status
+	d.setCacheStatus(CACHE_STATUS_MISS)
+
+	return nil
+}
+
+// setCacheStatus sets how the downloaded image was retrieved and records it
+// in the source cache's usage totals when caching is enabled
+func (d *Downloader) setCacheStatus(status string) {
+	// Bypass recording when caching is disabled
+	if d.cache == nil {
+		d.cacheStatus = CACHE_STATUS_BYPASS
+		return
+	}
+
+	d.cacheStatus = status
+	d.cache.Record(status)
+}
+
 // formUrl takes a URL string from a named request parameter, formats it,
 // and returns it fully formed when possible
 func (d *Downloader) formUrl(str string) (*url.URL, error) {
@@ -4,9 +4,12 @@ package utils
 import (
 	// Standard lib
 	"net/url"
+	"os"
+	"time"
 
 	// Internal
 	"github.com/marksost/img/helpers"
+	"github.com/marksost/img/tracing"
 
 	// Third-party
 	. "github.com/onsi/ginkgo"
@@ -79,6 +82,152 @@ var _ = Describe("downloader.go", func() {
 
 					// Verify return value
 					Expect(err).To(Not(HaveOccurred()))
+					Expect(d.CacheStatus()).To(Equal(CACHE_STATUS_BYPASS))
+				})
+			})
+
+			Context("When a request ID is set", func() {
+				BeforeEach(func() {
+					// Set url and request ID
+					d.url, _ = url.Parse(helpers.GetMockServer("request-id").URL)
+					d.SetRequestID("foo-request")
+				})
+
+				It("Forwards the request ID to the origin", func() {
+					// Call method
+					err := d.Download()
+
+					// Verify return value and the ID echoed back by the origin
+					Expect(err).To(Not(HaveOccurred()))
+					Expect(string(d.Data())).To(Equal("foo-request"))
+				})
+			})
+
+			Context("When the download is traced", func() {
+				var (
+					// Mock span to trace the download under
+					parent *tracing.Span
+				)
+
+				BeforeEach(func() {
+					// Enable tracing
+					tracing.SetExporter(tracing.NewFileExporter(os.DevNull))
+
+					// Set url and span
+					d.url, _ = url.Parse(helpers.GetMockServer("traceparent").URL)
+					parent = tracing.StartRootSpan("request", "")
+					d.SetSpan(parent)
+				})
+
+				AfterEach(func() {
+					// Disable tracing
+					tracing.SetExporter(nil)
+				})
+
+				It("Propagates the download's span to the origin", func() {
+					// Call method
+					err := d.Download()
+
+					// Verify return value and the trace context echoed back by the origin
+					Expect(err).To(Not(HaveOccurred()))
+					Expect(string(d.Data())).To(MatchRegexp("^00-" + parent.TraceID() + "-[0-9a-f]{16}-01$"))
+					Expect(string(d.Data())).To(Not(Equal(parent.Traceparent())))
+				})
+			})
+
+			Context("When the download isn't traced", func() {
+				BeforeEach(func() {
+					// Set url
+					d.url, _ = url.Parse(helpers.GetMockServer("traceparent").URL)
+				})
+
+				It("Doesn't send trace context to the origin", func() {
+					// Call method
+					err := d.Download()
+
+					// Verify return value and the trace context echoed back by the origin
+					Expect(err).To(Not(HaveOccurred()))
+					Expect(d.Data()).To(BeEmpty())
+				})
+			})
+
+			Context("When a fresh image is cached", func() {
+				BeforeEach(func() {
+					// Set url that would error if requested
+					d.url = &url.URL{Opaque: ":"}
+
+					// Set cache with a fresh entry
+					d.cache = NewSourceCache(time.Minute, 0, 0)
+					d.cache.Set(d.url.String(), &CacheEntry{Data: []byte("cached"), MimeType: "foo-mime"})
+				})
+
+				It("Uses the cached data without making a request", func() {
+					// Call method
+					err := d.Download()
+
+					// Verify return value
+					Expect(err).To(Not(HaveOccurred()))
+					Expect(string(d.Data())).To(Equal("cached"))
+					Expect(d.MimeType()).To(Equal("foo-mime"))
+					Expect(d.CacheStatus()).To(Equal(CACHE_STATUS_HIT))
+				})
+			})
+
+			Context("When a stale image is cached and unchanged at the origin", func() {
+				BeforeEach(func() {
+					// Set url
+					d.url, _ = url.Parse(helpers.GetMockServer("conditional").URL)
+
+					// Set cache with a stale entry
+					d.cache = NewSourceCache(time.Minute, 0, 0)
+					d.cache.Set(d.url.String(), &CacheEntry{
+						Data:     []byte("cached"),
+						ETag:     helpers.MOCK_ETAG,
+						StoredAt: time.Now().Add(-time.Hour),
+					})
+				})
+
+				It("Revalidates and reuses the cached data", func() {
+					// Call method
+					err := d.Download()
+
+					// Verify return value
+					Expect(err).To(Not(HaveOccurred()))
+					Expect(string(d.Data())).To(Equal("cached"))
+					Expect(d.CacheStatus()).To(Equal(CACHE_STATUS_REVALIDATED))
+
+					// Verify entry was refreshed
+					Expect(d.cache.IsFresh(d.cache.Get(d.url.String()))).To(BeTrue())
+				})
+			})
+
+			Context("When a stale image is cached and changed at the origin", func() {
+				BeforeEach(func() {
+					// Set url
+					d.url, _ = url.Parse(helpers.GetMockServer("conditional").URL)
+
+					// Set cache with a stale entry
+					d.cache = NewSourceCache(time.Minute, 0, 0)
+					d.cache.Set(d.url.String(), &CacheEntry{
+						Data:     []byte("cached"),
+						ETag:     `"outdated-etag"`,
+						StoredAt: time.Now().Add(-time.Hour),
+					})
+				})
+
+				It("Replaces the cached data and validators", func() {
+					// Call method
+					err := d.Download()
+
+					// Verify return value
+					Expect(err).To(Not(HaveOccurred()))
+					Expect(string(d.Data())).To(ContainSubstring("conditional"))
+					Expect(d.CacheStatus()).To(Equal(CACHE_STATUS_MISS))
+
+					// Verify entry was replaced
+					entry := d.cache.Get(d.url.String())
+					Expect(string(entry.Data)).To(ContainSubstring("conditional"))
+					Expect(entry.ETag).To(Equal(helpers.MOCK_ETAG))
 				})
 			})
 		})
@@ -132,6 +281,17 @@ var _ = Describe("downloader.go", func() {
 			})
 		})
 
+		Describe("`SetData` method", func() {
+			It("Sets the data and it's detected MIME type", func() {
+				// Call method
+				d.SetData([]byte("GIF89a"))
+
+				// Verify data and MIME type
+				Expect(d.Data()).To(Equal([]byte("GIF89a")))
+				Expect(d.MimeType()).To(Equal(GIF_MIME))
+			})
+		})
+
 		Describe("`Url` method", func() {
 			BeforeEach(func() {
 				// Set url
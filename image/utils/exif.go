@@ -0,0 +1,242 @@
+// exif contains all functionality around reading EXIF metadata
+// from JPEG and TIFF image data
+package utils
+
+import (
+	// Standard lib
+	"bytes"
+	"encoding/binary"
+	"strings"
+)
+
+const (
+	// Header EXIF data is prefixed with inside of a JPEG APP1 segment
+	EXIF_HEADER = "Exif\x00\x00"
+	// Tag of the IFD entry pointing to the EXIF sub-IFD
+	EXIF_IFD_POINTER_TAG = 0x8769
+	// Max number of entries read from a single IFD
+	// NOTE: Guards against malformed data claiming huge entry counts
+	EXIF_MAX_IFD_ENTRIES = 512
+
+	// JPEG markers used when searching for EXIF data
+	JPEG_MARKER_APP1 = 0xe1
+	JPEG_MARKER_EOI  = 0xd9
+	JPEG_MARKER_SOS  = 0xda
+)
+
+var (
+	// Map of EXIF tags to the names they're returned under
+	// NOTE: Only commonly used tags are read, and all others are ignored
+	exifTags = map[uint16]string{
+		0x010f: "Make",
+		0x0110: "Model",
+		0x0112: "Orientation",
+		0x011a: "XResolution",
+		0x011b: "YResolution",
+		0x0128: "ResolutionUnit",
+		0x0131: "Software",
+		0x0132: "DateTime",
+		0x013b: "Artist",
+		0x8298: "Copyright",
+		0x829a: "ExposureTime",
+		0x829d: "FNumber",
+		0x8827: "ISOSpeedRatings",
+		0x9003: "DateTimeOriginal",
+		0x9004: "DateTimeDigitized",
+		0x9209: "Flash",
+		0x920a: "FocalLength",
+		0xa001: "ColorSpace",
+		0xa002: "PixelXDimension",
+		0xa003: "PixelYDimension",
+		0xa434: "LensModel",
+	}
+	// Map of EXIF value types to the size (in bytes) of a single value
+	exifTypeSizes = map[uint16]uint32{
+		1:  1, // BYTE
+		2:  1, // ASCII
+		3:  2, // SHORT
+		4:  4, // LONG
+		5:  8, // RATIONAL
+		7:  1, // UNDEFINED
+		9:  4, // SLONG
+		10: 8, // SRATIONAL
+	}
+)
+
+// ReadExif reads EXIF metadata from JPEG or TIFF image data, and returns a map
+// of tag names to their values, or nil if no EXIF data was found
+func ReadExif(data []byte) map[string]interface{} {
+	// Find TIFF-formatted EXIF data
+	tiff := findExifData(data)
+	if len(tiff) < 8 {
+		return nil
+	}
+
+	// Determine byte order
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil
+	}
+
+	// Verify magic number
+	if order.Uint16(tiff[2:]) != 42 {
+		return nil
+	}
+
+	// Read IFD0, which links to the EXIF sub-IFD
+	tags := make(map[string]interface{})
+	readIFD(tiff, order, order.Uint32(tiff[4:]), tags, true)
+
+	if len(tags) == 0 {
+		return nil
+	}
+
+	return tags
+}
+
+// findExifData returns the TIFF-formatted EXIF data within image data,
+// or nil if none was found
+func findExifData(data []byte) []byte {
+	// TIFF images are EXIF data themselves
+	if bytes.HasPrefix(data, []byte("II*\x00")) || bytes.HasPrefix(data, []byte("MM\x00*")) {
+		return data
+	}
+
+	// Only JPEG images are otherwise supported
+	if len(data) < 4 || data[0] != 0xff || data[1] != 0xd8 {
+		return nil
+	}
+
+	// Loop through JPEG segments until the image data starts
+	for i := 2; i+4 <= len(data); {
+		// Verify segment starts with a marker
+		if data[i] != 0xff {
+			return nil
+		}
+
+		// Skip fill bytes
+		marker := data[i+1]
+		if marker == 0xff {
+			i++
+			continue
+		}
+
+		// Stop at the end of the headers
+		if marker == JPEG_MARKER_SOS || marker == JPEG_MARKER_EOI {
+			return nil
+		}
+
+		// Get segment, verifying it's length
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if length < 2 || i+2+length > len(data) {
+			return nil
+		}
+
+		segment := data[i+4 : i+2+length]
+
+		// Return EXIF data when found
+		if marker == JPEG_MARKER_APP1 && bytes.HasPrefix(segment, []byte(EXIF_HEADER)) {
+			return segment[len(EXIF_HEADER):]
+		}
+
+		i += 2 + length
+	}
+
+	return nil
+}
+
+// readIFD reads the known tags of a single IFD into a map, following the
+// pointer to the EXIF sub-IFD when allowed
+func readIFD(tiff []byte, order binary.ByteOrder, offset uint32, tags map[string]interface{}, follow bool) {
+	// Verify offset is within the data
+	if uint64(offset)+2 > uint64(len(tiff)) {
+		return
+	}
+
+	// Get number of entries
+	count := int(order.Uint16(tiff[offset:]))
+	if count > EXIF_MAX_IFD_ENTRIES {
+		return
+	}
+
+	// Loop through entries
+	for n := 0; n < count; n++ {
+		// Verify entry is within the data
+		start := uint64(offset) + 2 + uint64(n)*12
+		if start+12 > uint64(len(tiff)) {
+			return
+		}
+
+		entry := tiff[start : start+12]
+		tag := order.Uint16(entry)
+
+		// Follow EXIF sub-IFD pointer if allowed
+		if tag == EXIF_IFD_POINTER_TAG {
+			if follow {
+				readIFD(tiff, order, order.Uint32(entry[8:]), tags, false)
+			}
+			continue
+		}
+
+		// Read value of known tags
+		if name, ok := exifTags[tag]; ok {
+			if value := readExifValue(tiff, order, entry); value != nil {
+				tags[name] = value
+			}
+		}
+	}
+}
+
+// readExifValue reads the value of a single IFD entry, returning nil
+// if the value can't be read
+func readExifValue(tiff []byte, order binary.ByteOrder, entry []byte) interface{} {
+	// Get value type and count
+	valueType, count := order.Uint16(entry[2:]), order.Uint32(entry[4:])
+
+	size, ok := exifTypeSizes[valueType]
+	if !ok || count == 0 {
+		return nil
+	}
+
+	// Get raw value, which is stored in the entry itself when it fits
+	total := uint64(size) * uint64(count)
+	raw := entry[8:12]
+	if total > 4 {
+		offset := uint64(order.Uint32(entry[8:]))
+		if offset+total > uint64(len(tiff)) {
+			return nil
+		}
+
+		raw = tiff[offset : offset+total]
+	}
+
+	// Convert raw value based on it's type
+	switch valueType {
+	case 2:
+		return strings.TrimRight(string(raw[:total]), "\x00 ")
+	case 3:
+		return int(order.Uint16(raw))
+	case 4:
+		return int64(order.Uint32(raw))
+	case 9:
+		return int64(int32(order.Uint32(raw)))
+	case 5, 10:
+		num, den := order.Uint32(raw), order.Uint32(raw[4:])
+		if den == 0 {
+			return nil
+		}
+
+		if valueType == 10 {
+			return float64(int32(num)) / float64(int32(den))
+		}
+
+		return float64(num) / float64(den)
+	}
+
+	return nil
+}
@@ -0,0 +1,113 @@
+// Tests the exif.go file
+package utils
+
+import (
+	// Standard lib
+	"bytes"
+	"encoding/binary"
+
+	// Third-party
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// mockExifData returns little-endian TIFF-formatted EXIF data containing
+// an IFD0 with a make and orientation, and an EXIF sub-IFD with an exposure time
+func mockExifData() []byte {
+	buf := &bytes.Buffer{}
+	write := func(v interface{}) {
+		binary.Write(buf, binary.LittleEndian, v)
+	}
+
+	// Header, with IFD0 at offset 8
+	buf.WriteString("II")
+	write(uint16(42))
+	write(uint32(8))
+
+	// IFD0 (ends at offset 50)
+	write(uint16(3))
+	write([]uint16{0x010f, 2})
+	write([]uint32{6, 50})
+	write([]uint16{0x0112, 3})
+	write([]uint32{1, 6})
+	write([]uint16{EXIF_IFD_POINTER_TAG, 4})
+	write([]uint32{1, 56})
+	write(uint32(0))
+
+	// Make value (ends at offset 56)
+	buf.WriteString("Canon\x00")
+
+	// EXIF sub-IFD (ends at offset 74)
+	write(uint16(1))
+	write([]uint16{0x829a, 5})
+	write([]uint32{1, 74})
+	write(uint32(0))
+
+	// Exposure time value
+	write([]uint32{1, 100})
+
+	return buf.Bytes()
+}
+
+var _ = Describe("exif.go", func() {
+	Describe("`ReadExif` method", func() {
+		Context("With data containing no EXIF data", func() {
+			It("Returns nil", func() {
+				// Verify return values
+				Expect(ReadExif([]byte{0x47, 0x49, 0x46, 0x38})).To(BeNil())
+				Expect(ReadExif([]byte{0xff, 0xd8, 0xff, 0xda, 0x00, 0x02})).To(BeNil())
+			})
+		})
+
+		Context("With TIFF data", func() {
+			It("Returns the EXIF tags", func() {
+				// Call method
+				tags := ReadExif(mockExifData())
+
+				// Verify return value
+				Expect(tags).To(Equal(map[string]interface{}{
+					"ExposureTime": 0.01,
+					"Make":         "Canon",
+					"Orientation":  6,
+				}))
+			})
+		})
+
+		Context("With JPEG data", func() {
+			var (
+				// Mock JPEG data to use within tests
+				data []byte
+			)
+
+			BeforeEach(func() {
+				// Form APP1 segment
+				segment := append([]byte(EXIF_HEADER), mockExifData()...)
+				length := make([]byte, 2)
+				binary.BigEndian.PutUint16(length, uint16(len(segment)+2))
+
+				// Form JPEG with an APP0 segment before the APP1 segment
+				data = []byte{0xff, 0xd8, 0xff, 0xe0, 0x00, 0x04, 0x00, 0x00, 0xff, JPEG_MARKER_APP1}
+				data = append(data, length...)
+				data = append(data, segment...)
+				data = append(data, 0xff, JPEG_MARKER_EOI)
+			})
+
+			It("Returns the EXIF tags", func() {
+				// Call method
+				tags := ReadExif(data)
+
+				// Verify return value
+				Expect(tags).To(HaveKeyWithValue("Make", "Canon"))
+				Expect(tags).To(HaveKeyWithValue("Orientation", 6))
+				Expect(tags).To(HaveKeyWithValue("ExposureTime", 0.01))
+			})
+		})
+
+		Context("With truncated EXIF data", func() {
+			It("Returns nil", func() {
+				// Verify return value
+				Expect(ReadExif(mockExifData()[:20])).To(BeNil())
+			})
+		})
+	})
+})
@@ -0,0 +1,116 @@
+// limiter encapsulates all functionality around limiting the number of images
+// processed at once, with a bounded queue of requests waiting to be processed
+package utils
+
+import (
+	// Standard lib
+	"context"
+	"fmt"
+	"sync/atomic"
+)
+
+var (
+	// Error returned when a limiter's queue is full
+	ErrQueueFull = fmt.Errorf("Too many images are being processed. Please try again later")
+)
+
+type (
+	// Struct representing a concurrency limiter with a bounded wait queue
+	// NOTE: A nil limiter places no limits on concurrency
+	Limiter struct {
+		maxWaiting int64         // The max number of callers allowed to wait for a slot
+		slots      chan struct{} // Buffered channel holding one value per slot in use
+		waiting    int64         // The number of callers currently waiting for a slot
+	}
+)
+
+// NewLimiter creates a new `Limiter` and returns it
+// NOTE: Will return nil when `limit` is zero or less, meaning no limit
+func NewLimiter(limit, queue int) *Limiter {
+	// Check for no limit
+	if limit <= 0 {
+		return nil
+	}
+
+	return &Limiter{
+		maxWaiting: int64(queue),
+		slots:      make(chan struct{}, limit),
+	}
+}
+
+/* Begin main public functionality methods */
+
+// Acquire takes a slot, waiting for one to free up if needed
+// Will return an error without waiting if the wait queue is full
+func (l *Limiter) Acquire() error {
+	return l.AcquireContext(context.Background())
+}
+
+// AcquireContext takes a slot, waiting for one to free up until a context is done
+// Will return an error without waiting if the wait queue is full, or the context's
+// error if it's done before a slot frees up
+func (l *Limiter) AcquireContext(ctx context.Context) error {
+	// Check for no limit
+	if l == nil {
+		return nil
+	}
+
+	// Take a free slot without waiting if possible
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	// Join wait queue if there's room
+	if atomic.AddInt64(&l.waiting, 1) > l.maxWaiting {
+		atomic.AddInt64(&l.waiting, -1)
+		return ErrQueueFull
+	}
+
+	// Wait for a slot, or for the context to be done
+	defer atomic.AddInt64(&l.waiting, -1)
+
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Release frees up a slot previously taken by `Acquire`
+func (l *Limiter) Release() {
+	// Check for no limit
+	if l == nil {
+		return
+	}
+
+	<-l.slots
+}
+
+/* End main public functionality methods */
+
+/* Begin internal property methods */
+
+// InUse returns the number of slots currently taken
+func (l *Limiter) InUse() int {
+	// Check for no limit
+	if l == nil {
+		return 0
+	}
+
+	return len(l.slots)
+}
+
+// Waiting returns the number of callers currently waiting for a slot
+func (l *Limiter) Waiting() int64 {
+	// Check for no limit
+	if l == nil {
+		return 0
+	}
+
+	return atomic.LoadInt64(&l.waiting)
+}
+
+/* End internal property methods */
@@ -0,0 +1,160 @@
+// Tests the limiter.go file
+package utils
+
+import (
+	// Standard lib
+	"context"
+	"time"
+
+	// Third-party
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("limiter.go", func() {
+	var (
+		// Mock limiter to test
+		l *Limiter
+	)
+
+	BeforeEach(func() {
+		// Create mock limiter
+		l = NewLimiter(1, 1)
+	})
+
+	Describe("`NewLimiter` method", func() {
+		Context("With no limit", func() {
+			It("Returns nil", func() {
+				// Verify return value
+				Expect(NewLimiter(0, 10)).To(BeNil())
+			})
+		})
+
+		Context("With a limit", func() {
+			It("Returns a valid Limiter instance", func() {
+				// Call method
+				l := NewLimiter(2, 10)
+
+				// Verify return value
+				Expect(l).To(Not(BeNil()))
+				Expect(cap(l.slots)).To(Equal(2))
+			})
+		})
+	})
+
+	Describe("Limiter public functionality methods", func() {
+		Describe("`Acquire` and `Release` methods", func() {
+			Context("With a nil limiter", func() {
+				It("Never limits", func() {
+					// Reset limiter
+					l = nil
+
+					// Verify return values
+					Expect(l.Acquire()).To(Not(HaveOccurred()))
+					Expect(l.Acquire()).To(Not(HaveOccurred()))
+					l.Release()
+					Expect(l.InUse()).To(Equal(0))
+				})
+			})
+
+			Context("With a free slot", func() {
+				It("Takes the slot without waiting", func() {
+					// Call method
+					err := l.Acquire()
+
+					// Verify return value
+					Expect(err).To(Not(HaveOccurred()))
+					Expect(l.InUse()).To(Equal(1))
+
+					// Release slot
+					l.Release()
+					Expect(l.InUse()).To(Equal(0))
+				})
+			})
+
+			Context("With no free slots and room in the queue", func() {
+				BeforeEach(func() {
+					// Take only slot
+					l.Acquire()
+				})
+
+				It("Waits for a slot to be released", func() {
+					// Acquire in the background
+					acquired := make(chan error)
+					go func() {
+						acquired <- l.Acquire()
+					}()
+
+					// Verify caller is waiting
+					Eventually(l.Waiting).Should(BeEquivalentTo(1))
+					Consistently(acquired, 50*time.Millisecond).ShouldNot(Receive())
+
+					// Release slot and verify it was acquired
+					l.Release()
+					Eventually(acquired).Should(Receive(BeNil()))
+					Expect(l.Waiting()).To(BeEquivalentTo(0))
+				})
+			})
+
+			Context("With no free slots and a full queue", func() {
+				var (
+					// Function used to stop the queued caller waiting
+					cancel context.CancelFunc
+					// Channel receiving the queued caller's result
+					queued chan error
+				)
+
+				BeforeEach(func() {
+					// Take only slot and fill queue
+					l.Acquire()
+
+					var ctx context.Context
+					ctx, cancel = context.WithCancel(context.Background())
+					queued = make(chan error)
+					go func() {
+						queued <- l.AcquireContext(ctx)
+					}()
+
+					Eventually(l.Waiting).Should(BeEquivalentTo(1))
+				})
+
+				AfterEach(func() {
+					// Stop queued caller waiting and verify it returned
+					cancel()
+					Eventually(queued).Should(Receive(Equal(context.Canceled)))
+				})
+
+				It("Returns an error without waiting", func() {
+					// Call method
+					err := l.Acquire()
+
+					// Verify return value
+					Expect(err).To(Equal(ErrQueueFull))
+				})
+			})
+		})
+
+		Describe("`AcquireContext` method", func() {
+			Context("With no free slots and a context that's done first", func() {
+				BeforeEach(func() {
+					// Take only slot
+					l.Acquire()
+				})
+
+				It("Stops waiting and returns the context's error", func() {
+					// Create context
+					ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+					defer cancel()
+
+					// Call method
+					err := l.AcquireContext(ctx)
+
+					// Verify return value and queue
+					Expect(err).To(Equal(context.DeadlineExceeded))
+					Expect(l.Waiting()).To(BeEquivalentTo(0))
+					Expect(l.InUse()).To(Equal(1))
+				})
+			})
+		})
+	})
+})
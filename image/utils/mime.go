@@ -5,6 +5,7 @@ package utils
 import (
 	// Standard lib
 	"bytes"
+	"encoding/binary"
 )
 
 const (
@@ -12,33 +13,246 @@ const (
 	DEFAULT_MIME_TYPE = "application/octet-stream"
 
 	// MIME types for all supported image formats
+	APNG_MIME = "image/apng"
 	GIF_MIME  = "image/gif"
 	JPEG_MIME = "image/jpeg"
 	PNG_MIME  = "image/png"
 	TIFF_MIME = "image/tiff"
+	WEBP_MIME = "image/webp"
+
+	// MIME types for image formats that are detected, but not supported
+	AVIF_MIME = "image/avif"
+	BMP_MIME  = "image/bmp"
+	HEIC_MIME = "image/heic"
+	ICO_MIME  = "image/x-icon"
+	SVG_MIME  = "image/svg+xml"
+
+	// Number of bytes searched for the root element of SVG images
+	SVG_SNIFF_LENGTH = 1024
 )
 
 var (
-	// Map of MIME types to the first two bytes of their data
-	// NOTE: Used to identify what type of image is being requested
-	// NOTE: Different image types all have consistent starting byte patterns
-	MimeTypes = map[string][]byte{
-		GIF_MIME:  []byte{0x47, 0x49},
-		JPEG_MIME: []byte{0xff, 0xd8},
-		PNG_MIME:  []byte{0x89, 0x50},
-		TIFF_MIME: []byte{0x49, 0x49},
+	// Slice of signatures used to identify what type of image is being requested,
+	// checked in order
+	// NOTE: Stronger signatures are checked before weaker ones, so that short
+	// signatures can't shadow longer ones
+	MimeSignatures = []MimeSignature{
+		{MimeType: JPEG_MIME, Match: prefixMatcher(0, "\xff\xd8\xff")},
+		{MimeType: PNG_MIME, Match: prefixMatcher(0, "\x89PNG\r\n\x1a\n")},
+		{MimeType: GIF_MIME, Match: anyMatcher(prefixMatcher(0, "GIF87a"), prefixMatcher(0, "GIF89a"))},
+		{MimeType: WEBP_MIME, Match: allMatcher(prefixMatcher(0, "RIFF"), prefixMatcher(8, "WEBP"))},
+		{MimeType: TIFF_MIME, Match: anyMatcher(prefixMatcher(0, "II*\x00"), prefixMatcher(0, "MM\x00*"))},
+		{MimeType: AVIF_MIME, Match: brandMatcher("avif", "avis")},
+		{MimeType: HEIC_MIME, Match: brandMatcher("heic", "heix", "heim", "heis", "hevc", "hevx")},
+		{MimeType: ICO_MIME, Match: isIco},
+		{MimeType: BMP_MIME, Match: isBmp},
+		{MimeType: SVG_MIME, Match: isSvg},
+	}
+	// Slice of MIME types images can be output as
+	// NOTE: Includes formats images can be converted to, but aren't detected as
+	OutputMimeTypes = []string{GIF_MIME, JPEG_MIME, PNG_MIME, TIFF_MIME, WEBP_MIME}
+)
+
+type (
+	// Struct representing the signature of an image format, used to identify
+	// the format from the start of an image's data
+	MimeSignature struct {
+		Match    func([]byte) bool // Returns a boolean indicating if data matches the signature
+		MimeType string            // The MIME type of data matching the signature
 	}
 )
 
 // getMimeType attempts to determine the correct MIME type for a given byte slice
 // Will return a default MIME type when no match is found
 func getMimeType(data []byte) string {
-	// Loop through defined types, checking the first two bytes of the input
-	for mime, slice := range MimeTypes {
-		if bytes.Equal(data[:2], slice) {
-			return mime
+	// Loop through signatures in order, checking the input against each
+	for _, signature := range MimeSignatures {
+		if !signature.Match(data) {
+			continue
 		}
+
+		// Check for animated PNGs
+		// NOTE: Animated PNGs share their signature with PNGs
+		if signature.MimeType == PNG_MIME && IsAnimatedPng(data) {
+			return APNG_MIME
+		}
+
+		return signature.MimeType
 	}
 
 	return DEFAULT_MIME_TYPE
 }
+
+// IsAnimatedPng returns a boolean indicating if PNG data is animated, by looking
+// for an animation control chunk before the image data
+// See https://wiki.mozilla.org/APNG_Specification for more information
+func IsAnimatedPng(data []byte) bool {
+	// Skip signature, looping through chunks
+	// NOTE: Each chunk is made up of it's length, type, data and a checksum
+	for offset := 8; offset+8 <= len(data); {
+		length := int(binary.BigEndian.Uint32(data[offset:]))
+
+		switch string(data[offset+4 : offset+8]) {
+		case "acTL":
+			return true
+		case "IDAT":
+			return false
+		}
+
+		// Verify chunk length
+		if length < 0 || length > len(data) {
+			return false
+		}
+
+		offset += 12 + length
+	}
+
+	return false
+}
+
+/* Begin signature matchers */
+
+// allMatcher returns a matcher that matches data matching all of a set of matchers
+func allMatcher(matchers ...func([]byte) bool) func([]byte) bool {
+	return func(data []byte) bool {
+		for _, match := range matchers {
+			if !match(data) {
+				return false
+			}
+		}
+
+		return true
+	}
+}
+
+// anyMatcher returns a matcher that matches data matching any of a set of matchers
+func anyMatcher(matchers ...func([]byte) bool) func([]byte) bool {
+	return func(data []byte) bool {
+		for _, match := range matchers {
+			if match(data) {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// brandMatcher returns a matcher that matches ISO base media files (ex: AVIF, HEIC)
+// with any of a set of brands as their major or compatible brands
+// See https://www.iso.org/standard/68960.html for more information
+func brandMatcher(brands ...string) func([]byte) bool {
+	return func(data []byte) bool {
+		// Verify data starts with a file type box
+		// NOTE: Boxes are made up of their size, type and data
+		if len(data) < 16 || string(data[4:8]) != "ftyp" {
+			return false
+		}
+
+		// Limit brands to those within the box and data
+		size := int(binary.BigEndian.Uint32(data))
+		if size < 16 || size > len(data) {
+			size = len(data)
+		}
+
+		// Loop through major brand and compatible brands
+		// NOTE: The minor version between them is skipped
+		for offset := 8; offset+4 <= size; offset += 4 {
+			if offset == 12 {
+				continue
+			}
+
+			for _, brand := range brands {
+				if string(data[offset:offset+4]) == brand {
+					return true
+				}
+			}
+		}
+
+		return false
+	}
+}
+
+// prefixMatcher returns a matcher that matches data containing a prefix at an offset
+func prefixMatcher(offset int, prefix string) func([]byte) bool {
+	return func(data []byte) bool {
+		return len(data) >= offset+len(prefix) && string(data[offset:offset+len(prefix)]) == prefix
+	}
+}
+
+// isBmp returns a boolean indicating if data is a BMP image
+// NOTE: The two-byte signature is checked along with the size of the header that follows,
+// as the signature alone is too short to be reliable
+func isBmp(data []byte) bool {
+	if len(data) < 18 || string(data[:2]) != "BM" {
+		return false
+	}
+
+	switch binary.LittleEndian.Uint32(data[14:]) {
+	case 12, 40, 52, 56, 64, 108, 124:
+		return true
+	}
+
+	return false
+}
+
+// isIco returns a boolean indicating if data is an ICO image containing at least one image
+func isIco(data []byte) bool {
+	return len(data) >= 6 && bytes.Equal(data[:4], []byte{0x00, 0x00, 0x01, 0x00}) && binary.LittleEndian.Uint16(data[4:]) > 0
+}
+
+// isSvg returns a boolean indicating if data is an SVG image, by looking for
+// an SVG root element at the start of an XML document
+// NOTE: The root element may be preceded by an XML declaration, a doctype and comments
+func isSvg(data []byte) bool {
+	// Limit data searched
+	if len(data) > SVG_SNIFF_LENGTH {
+		data = data[:SVG_SNIFF_LENGTH]
+	}
+
+	data = bytes.ToLower(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+
+	// Skip markup before the root element
+	for {
+		data = bytes.TrimLeft(data, " \t\r\n")
+
+		var end string
+		switch {
+		case bytes.HasPrefix(data, []byte("<?")):
+			end = "?>"
+		case bytes.HasPrefix(data, []byte("<!--")):
+			end = "-->"
+		case bytes.HasPrefix(data, []byte("<!doctype")):
+			end = ">"
+
+			// Skip internal subset of declarations, which may contain the end of the doctype
+			if i := bytes.IndexAny(data, "[>"); i >= 0 && data[i] == '[' {
+				if i = bytes.IndexByte(data, ']'); i < 0 {
+					return false
+				}
+
+				data = data[i:]
+			}
+		}
+
+		if end == "" {
+			break
+		}
+
+		i := bytes.Index(data, []byte(end))
+		if i < 0 {
+			return false
+		}
+
+		data = data[i+len(end):]
+	}
+
+	// Verify root element is an SVG element
+	if len(data) < 5 || !bytes.HasPrefix(data, []byte("<svg")) {
+		return false
+	}
+
+	return bytes.IndexByte([]byte(" \t\r\n/>"), data[4]) >= 0
+}
+
+/* End signature matchers */
@@ -2,6 +2,10 @@
 package utils
 
 import (
+	// Standard lib
+	"io/ioutil"
+	"path"
+
 	// Third-party
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -10,30 +14,109 @@ import (
 var _ = Describe("mime.go", func() {
 	Describe("`getMimeType` method", func() {
 		var (
-			// Input for `getMimeType` input
-			input map[string][]byte
+			// Input for `getMimeType` method, in order of expected return values
+			input []struct {
+				data     []byte
+				expected string
+			}
 		)
 
 		BeforeEach(func() {
 			// Set input
-			input = map[string][]byte{
-				"application/octet-stream": []byte{0x99, 0x99}, // NOTE: Tests fallback
-				"image/gif":                []byte{0x47, 0x49},
-				"image/jpeg":               []byte{0xff, 0xd8},
-				"image/png":                []byte{0x89, 0x50},
-				"image/tiff":               []byte{0x49, 0x49},
+			input = []struct {
+				data     []byte
+				expected string
+			}{
+				{[]byte{0x99, 0x99}, DEFAULT_MIME_TYPE}, // NOTE: Tests fallback
+				{[]byte{}, DEFAULT_MIME_TYPE},           // NOTE: Tests empty input
+				{[]byte{0xff}, DEFAULT_MIME_TYPE},       // NOTE: Tests input shorter than signatures
+				{[]byte{0xff, 0xd8}, DEFAULT_MIME_TYPE}, // NOTE: Tests partial signatures
+				{[]byte("GIF87a"), GIF_MIME},
+				{[]byte("GIF89a"), GIF_MIME},
+				{[]byte{0xff, 0xd8, 0xff, 0xe0}, JPEG_MIME},
+				{[]byte("\x89PNG\r\n\x1a\n"), PNG_MIME},
+				{[]byte("II*\x00"), TIFF_MIME},
+				{[]byte("MM\x00*"), TIFF_MIME},
+				{[]byte("RIFF\x00\x00\x00\x00WEBPVP8 "), WEBP_MIME},
+				{[]byte("RIFF\x00\x00\x00\x00WAVEfmt "), DEFAULT_MIME_TYPE}, // NOTE: Tests other RIFF formats
+				{[]byte("\x00\x00\x00\x1cftypavif\x00\x00\x00\x00avifmif1miaf"), AVIF_MIME},
+				{[]byte("\x00\x00\x00\x18ftypmif1\x00\x00\x00\x00mif1heic"), HEIC_MIME},     // NOTE: Tests compatible brands
+				{[]byte("\x00\x00\x00\x10ftypmp42\x00\x00\x00\x00avif"), DEFAULT_MIME_TYPE}, // NOTE: Tests brands outside of the box
+				{[]byte("\x00\x00\x01\x00\x01\x00"), ICO_MIME},
+				{[]byte("\x00\x00\x01\x00\x00\x00"), DEFAULT_MIME_TYPE}, // NOTE: Tests ICOs without images
+				{append([]byte("BM\x00\x00\x00\x00\x00\x00\x00\x00\x36\x00\x00\x00"), 40, 0, 0, 0), BMP_MIME},
+				{[]byte("BMP is a format"), DEFAULT_MIME_TYPE}, // NOTE: Tests text starting with "BM"
+				{[]byte("<svg xmlns=\"http://www.w3.org/2000/svg\"></svg>"), SVG_MIME},
+				{[]byte("\xef\xbb\xbf\n<?xml version=\"1.0\"?>\n<SVG></SVG>"), SVG_MIME},
+				{[]byte("<html><body></body></html>"), DEFAULT_MIME_TYPE},
+				{[]byte("text with <svg> in it"), DEFAULT_MIME_TYPE},
+				{[]byte("<!-- Comment -->\n<!DOCTYPE svg PUBLIC \"-//W3C//DTD SVG 1.1//EN\" \"svg11.dtd\">\n<svg/>"), SVG_MIME},
+				{[]byte("<?xml version=\"1.0\"?><!DOCTYPE svg [<!ENTITY foo \"bar\">]><svg>"), SVG_MIME}, // NOTE: Tests doctypes with internal subsets
+				{[]byte("<html><body><svg></svg></body></html>"), DEFAULT_MIME_TYPE},                     // NOTE: Tests documents with embedded SVGs
+				{[]byte("<svgfoo></svgfoo>"), DEFAULT_MIME_TYPE},
+				{[]byte("<!-- <svg> is commented out"), DEFAULT_MIME_TYPE},
 			}
 		})
 
 		It("Returns either a matching MIME type or a default", func() {
 			// Loop through test data
-			for expected, input := range input {
+			for _, input := range input {
 				// Call method
-				actual := getMimeType(input)
+				actual := getMimeType(input.data)
 
 				// Verify return value
-				Expect(actual).To(Equal(expected))
+				Expect(actual).To(Equal(input.expected), "%q", input.data)
+			}
+		})
+
+		It("Handles truncated input without panicking", func() {
+			// Loop through test data, checking every prefix of each input
+			for _, input := range input {
+				for i := 0; i <= len(input.data); i++ {
+					Expect(func() { getMimeType(input.data[:i]) }).To(Not(Panic()))
+				}
+			}
+		})
+
+		It("Detects the MIME type of test images", func() {
+			// Verify return values
+			for file, expected := range map[string]string{"1x1.gif": GIF_MIME, "1x1.jpg": JPEG_MIME} {
+				data, err := ioutil.ReadFile(path.Join("../../test/images", file))
+				Expect(err).To(Not(HaveOccurred()))
+				Expect(getMimeType(data)).To(Equal(expected))
 			}
 		})
+
+		Context("With an animated PNG", func() {
+			It("Returns the animated PNG MIME type", func() {
+				// Form PNG with an animation control chunk before it's image data
+				data := []byte("\x89PNG\r\n\x1a\n")
+				data = append(data, 0, 0, 0, 0)
+				data = append(data, []byte("IHDR")...)
+				data = append(data, 0, 0, 0, 0)
+				data = append(data, 0, 0, 0, 8)
+				data = append(data, []byte("acTL")...)
+
+				// Verify return values
+				Expect(getMimeType(data)).To(Equal(APNG_MIME))
+				Expect(IsAnimatedPng(data)).To(BeTrue())
+			})
+		})
+	})
+
+	Describe("`IsAnimatedPng` method", func() {
+		It("Returns false for PNGs without an animation control chunk before their image data", func() {
+			// Form PNG with image data before an animation control chunk
+			data := []byte("\x89PNG\r\n\x1a\n")
+			data = append(data, 0, 0, 0, 0)
+			data = append(data, []byte("IDAT")...)
+			data = append(data, 0, 0, 0, 0)
+			data = append(data, 0, 0, 0, 8)
+			data = append(data, []byte("acTL")...)
+
+			// Verify return values
+			Expect(IsAnimatedPng(data)).To(BeFalse())
+			Expect(IsAnimatedPng([]byte{0x89, 0x50})).To(BeFalse())
+		})
 	})
 })
@@ -6,10 +6,13 @@ import (
 	"os"
 	"os/signal"
 	"runtime"
+	"syscall"
 
 	// Internal
 	"github.com/marksost/img/config"
+	"github.com/marksost/img/image"
 	"github.com/marksost/img/server"
+	"github.com/marksost/img/tracing"
 
 	// Third-party
 	log "github.com/Sirupsen/logrus"
@@ -29,26 +32,40 @@ func main() {
 	c := config.GetInstance()
 
 	// Log configuration value only in development environments
+	// NOTE: Secrets are redacted so they don't end up in logs
 	if c.IsDevelopment() {
-		log.WithField("config", c).Info("Configuration")
+		log.WithField("config", c.Redacted()).Info("Configuration")
 	}
 
 	// Parse flags
 	flag.Parse()
 
+	// Initialize image processing utilities
+	image.Init()
+
+	// Initialize tracing
+	tracing.Init()
+
 	// Start server
 	server.Start()
 
-	// Listen for and exit the application on SIGKILL or SIGINT
-	stop := make(chan os.Signal)
-	signal.Notify(stop, os.Interrupt, os.Kill)
+	// Listen for and exit the application on SIGTERM or SIGINT
+	// NOTE: SIGKILL can't be caught, so isn't listened for
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
 
 	select {
-	case <-stop:
-		// Attempt to stop the server
+	case sig := <-stop:
+		// Log shut down
+		log.WithField("signal", sig.String()).Info("Server is shutting down")
+
+		// Attempt to gracefully stop the server
 		server.Stop()
 
-		// Log shut down
-		log.Info("Server is shutting down")
+		// Export any remaining spans
+		tracing.Shutdown()
+
+		// Log stop
+		log.Info("Server has stopped")
 	}
 }
@@ -0,0 +1,340 @@
+// metrics package defines the building blocks for recording application metrics
+// and exporting them in the Prometheus text exposition format
+// See https://prometheus.io/docs/instrumenting/exposition_formats/ for more information
+package metrics
+
+import (
+	// Standard lib
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	// Internal
+	"github.com/marksost/img/helpers"
+)
+
+const (
+	// The content type metrics are exported with
+	CONTENT_TYPE = "text/plain; version=0.0.4; charset=utf-8"
+	// The delimiter used when joining label values into a key
+	LABEL_VALUES_DELIMITER = "\xff"
+)
+
+var (
+	// Default histogram buckets (in seconds) for latency metrics
+	DurationBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+	// Default histogram buckets (in bytes) for size metrics
+	SizeBuckets = []float64{1 << 10, 10 << 10, 100 << 10, 500 << 10, 1 << 20, 5 << 20, 10 << 20, 50 << 20}
+
+	// Slice of all registered metrics, in registration order
+	registry []collector
+	// Mutex used to synchronize access to the registry
+	registryMutex sync.Mutex
+)
+
+type (
+	// Interface all metrics must satisfy to be exported
+	collector interface {
+		write(*bytes.Buffer)
+	}
+	// Struct representing a metric whose value only ever increases
+	Counter struct {
+		*family
+		values map[string]float64 // Map of label value keys to their counts
+	}
+	// Struct representing a metric whose value is read when exported
+	GaugeFunc struct {
+		*family
+		fn func() float64 // Function returning the current value of the gauge
+	}
+	// Struct representing a metric that samples observations into buckets
+	Histogram struct {
+		*family
+		buckets []float64                  // Upper bounds of the histogram's buckets
+		values  map[string]*histogramValue // Map of label value keys to their observations
+	}
+	// Struct representing the information shared by all metric types
+	family struct {
+		help       string     // Description of the metric
+		labels     []string   // Names of the labels the metric is partitioned by
+		mutex      sync.Mutex // Mutex used to synchronize access to the metric's values
+		name       string     // Name of the metric
+		metricType string     // The Prometheus type of the metric
+	}
+	// Struct representing the observations of a single histogram partition
+	histogramValue struct {
+		counts []uint64 // Number of observations per bucket (non-cumulative)
+		count  uint64   // Total number of observations
+		sum    float64  // Sum of all observations
+	}
+)
+
+// NewCounter creates and registers a new `Counter` and returns it
+func NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{
+		family: newFamily(name, help, "counter", labels),
+		values: make(map[string]float64),
+	}
+
+	register(c)
+
+	return c
+}
+
+// NewGaugeFunc creates and registers a new `GaugeFunc` and returns it
+func NewGaugeFunc(name, help string, fn func() float64) *GaugeFunc {
+	g := &GaugeFunc{
+		family: newFamily(name, help, "gauge", nil),
+		fn:     fn,
+	}
+
+	register(g)
+
+	return g
+}
+
+// NewHistogram creates and registers a new `Histogram` and returns it
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	h := &Histogram{
+		family:  newFamily(name, help, "histogram", labels),
+		buckets: buckets,
+		values:  make(map[string]*histogramValue),
+	}
+
+	register(h)
+
+	return h
+}
+
+/* Begin counter methods */
+
+// Add increases the counter for a set of label values by a given amount
+func (c *Counter) Add(v float64, labelValues ...string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.values[c.key(labelValues)] += v
+}
+
+// Inc increases the counter for a set of label values by one
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Value returns the current count for a set of label values
+func (c *Counter) Value(labelValues ...string) float64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.values[c.key(labelValues)]
+}
+
+// write writes the counter in the text exposition format
+func (c *Counter) write(buf *bytes.Buffer) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.writeHeader(buf)
+
+	for _, key := range sortedKeys(c.values) {
+		c.writeSample(buf, c.name, key, "", c.values[key])
+	}
+}
+
+/* End counter methods */
+
+/* Begin gauge methods */
+
+// write writes the gauge in the text exposition format
+func (g *GaugeFunc) write(buf *bytes.Buffer) {
+	g.writeHeader(buf)
+	g.writeSample(buf, g.name, "", "", g.fn())
+}
+
+/* End gauge methods */
+
+/* Begin histogram methods */
+
+// Observe adds a single observation to the histogram for a set of label values
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	// Get or create partition
+	key := h.key(labelValues)
+	hv, ok := h.values[key]
+	if !ok {
+		hv = &histogramValue{counts: make([]uint64, len(h.buckets))}
+		h.values[key] = hv
+	}
+
+	// Add observation to the first bucket it fits in
+	for i, upper := range h.buckets {
+		if v <= upper {
+			hv.counts[i]++
+			break
+		}
+	}
+
+	hv.count++
+	hv.sum += v
+}
+
+// Count returns the number of observations for a set of label values
+func (h *Histogram) Count(labelValues ...string) uint64 {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if hv, ok := h.values[h.key(labelValues)]; ok {
+		return hv.count
+	}
+
+	return 0
+}
+
+// write writes the histogram in the text exposition format
+func (h *Histogram) write(buf *bytes.Buffer) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.writeHeader(buf)
+
+	// Get sorted keys
+	keys := make([]string, 0, len(h.values))
+	for key := range h.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	// Loop through partitions, writing cumulative buckets, sum and count
+	for _, key := range keys {
+		hv := h.values[key]
+
+		var cumulative uint64
+		for i, upper := range h.buckets {
+			cumulative += hv.counts[i]
+			h.writeSample(buf, h.name+"_bucket", key, formatFloat(upper), float64(cumulative))
+		}
+
+		h.writeSample(buf, h.name+"_bucket", key, "+Inf", float64(hv.count))
+		h.writeSample(buf, h.name+"_sum", key, "", hv.sum)
+		h.writeSample(buf, h.name+"_count", key, "", float64(hv.count))
+	}
+}
+
+/* End histogram methods */
+
+/* Begin family methods */
+
+// newFamily creates a new `family` and returns it
+func newFamily(name, help, metricType string, labels []string) *family {
+	return &family{
+		help:       help,
+		labels:     labels,
+		metricType: metricType,
+		name:       name,
+	}
+}
+
+// key joins a set of label values into a key used to store a partition's value
+// NOTE: Missing label values are treated as empty, and extra values are ignored
+func (f *family) key(labelValues []string) string {
+	values := make([]string, len(f.labels))
+	copy(values, labelValues)
+
+	return strings.Join(values, LABEL_VALUES_DELIMITER)
+}
+
+// writeHeader writes the metric's HELP and TYPE lines
+func (f *family) writeHeader(buf *bytes.Buffer) {
+	fmt.Fprintf(buf, "# HELP %s %s\n", f.name, escape(f.help, false))
+	fmt.Fprintf(buf, "# TYPE %s %s\n", f.name, f.metricType)
+}
+
+// writeSample writes a single sample line for a partition of the metric
+// NOTE: A non-empty `le` adds the histogram bucket label to the sample
+func (f *family) writeSample(buf *bytes.Buffer, name, key, le string, v float64) {
+	// Form label pairs
+	pairs := make([]string, 0, len(f.labels)+1)
+	if len(f.labels) > 0 {
+		for i, value := range strings.Split(key, LABEL_VALUES_DELIMITER) {
+			pairs = append(pairs, f.labels[i]+`="`+escape(value, true)+`"`)
+		}
+	}
+
+	if le != "" {
+		pairs = append(pairs, `le="`+le+`"`)
+	}
+
+	// Write sample
+	buf.WriteString(name)
+	if len(pairs) > 0 {
+		buf.WriteString("{" + strings.Join(pairs, ",") + "}")
+	}
+	buf.WriteString(" " + formatFloat(v) + "\n")
+}
+
+/* End family methods */
+
+// Export returns all registered metrics in the text exposition format
+func Export() string {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+
+	// Write each metric in turn
+	buf := &bytes.Buffer{}
+	for _, c := range registry {
+		c.write(buf)
+	}
+
+	return buf.String()
+}
+
+// escape escapes a help string or label value for the text exposition format
+func escape(str string, quotes bool) string {
+	str = strings.Replace(str, `\`, `\\`, -1)
+	str = strings.Replace(str, "\n", `\n`, -1)
+
+	if quotes {
+		str = strings.Replace(str, `"`, `\"`, -1)
+	}
+
+	return str
+}
+
+// formatFloat formats a sample value for the text exposition format
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+
+	return helpers.Float642String(v)
+}
+
+// register adds a metric to the registry
+func register(c collector) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+
+	registry = append(registry, c)
+}
+
+// sortedKeys returns the keys of a counter's values in sorted order
+func sortedKeys(values map[string]float64) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
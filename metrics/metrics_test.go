@@ -0,0 +1,111 @@
+// Tests the metrics.go file
+package metrics
+
+import (
+	// Third-party
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("metrics.go", func() {
+	var (
+		// Mock counter to test
+		c *Counter
+		// Mock gauge to test
+		g *GaugeFunc
+		// Mock histogram to test
+		h *Histogram
+	)
+
+	BeforeEach(func() {
+		// Reset registry
+		registry = nil
+
+		// Create mock metrics
+		c = NewCounter("test_counter_total", "A test counter.", "code")
+		g = NewGaugeFunc("test_gauge", "A test gauge.", func() float64 { return 0.5 })
+		h = NewHistogram("test_histogram_seconds", "A test histogram.", []float64{1, 5}, "operation")
+	})
+
+	Describe("`NewCounter`, `NewGaugeFunc` and `NewHistogram` methods", func() {
+		It("Registers and returns new metrics", func() {
+			// Verify return values
+			Expect(c).To(Not(BeNil()))
+			Expect(g).To(Not(BeNil()))
+			Expect(h).To(Not(BeNil()))
+
+			// Verify metrics were registered
+			Expect(len(registry)).To(Equal(3))
+		})
+	})
+
+	Describe("Counter methods", func() {
+		Describe("`Add` and `Inc` methods", func() {
+			It("Increases the count for a set of label values", func() {
+				// Call methods
+				c.Inc("200")
+				c.Add(2, "200")
+				c.Inc("404")
+
+				// Verify values
+				Expect(c.Value("200")).To(Equal(3.0))
+				Expect(c.Value("404")).To(Equal(1.0))
+				Expect(c.Value("500")).To(Equal(0.0))
+			})
+		})
+	})
+
+	Describe("Histogram methods", func() {
+		Describe("`Observe` method", func() {
+			It("Adds observations for a set of label values", func() {
+				// Call method
+				h.Observe(0.5, "resize")
+				h.Observe(10, "resize")
+
+				// Verify values
+				Expect(h.Count("resize")).To(BeEquivalentTo(2))
+				Expect(h.Count("crop")).To(BeEquivalentTo(0))
+			})
+		})
+	})
+
+	Describe("`Export` method", func() {
+		BeforeEach(func() {
+			// Record values
+			c.Inc("200")
+			h.Observe(0.5, "resize")
+			h.Observe(3, "resize")
+			h.Observe(10, "resize")
+		})
+
+		It("Returns all metrics in the text exposition format", func() {
+			// Call method
+			output := Export()
+
+			// Verify counter output
+			Expect(output).To(ContainSubstring("# HELP test_counter_total A test counter.\n"))
+			Expect(output).To(ContainSubstring("# TYPE test_counter_total counter\n"))
+			Expect(output).To(ContainSubstring(`test_counter_total{code="200"} 1` + "\n"))
+
+			// Verify gauge output
+			Expect(output).To(ContainSubstring("# TYPE test_gauge gauge\n"))
+			Expect(output).To(ContainSubstring("test_gauge 0.5\n"))
+
+			// Verify histogram output
+			Expect(output).To(ContainSubstring("# TYPE test_histogram_seconds histogram\n"))
+			Expect(output).To(ContainSubstring(`test_histogram_seconds_bucket{operation="resize",le="1"} 1` + "\n"))
+			Expect(output).To(ContainSubstring(`test_histogram_seconds_bucket{operation="resize",le="5"} 2` + "\n"))
+			Expect(output).To(ContainSubstring(`test_histogram_seconds_bucket{operation="resize",le="+Inf"} 3` + "\n"))
+			Expect(output).To(ContainSubstring(`test_histogram_seconds_sum{operation="resize"} 13.5` + "\n"))
+			Expect(output).To(ContainSubstring(`test_histogram_seconds_count{operation="resize"} 3` + "\n"))
+		})
+	})
+
+	Describe("`escape` method", func() {
+		It("Escapes backslashes, new lines and (optionally) quotes", func() {
+			// Verify return values
+			Expect(escape(`a\b`+"\n"+`"c"`, false)).To(Equal(`a\\b\n"c"`))
+			Expect(escape(`a\b`+"\n"+`"c"`, true)).To(Equal(`a\\b\n\"c\"`))
+		})
+	})
+})
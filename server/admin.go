@@ -0,0 +1,219 @@
+// admin contains all administrative route functionality, such as inspecting
+// and purging the source image cache
+package server
+
+import (
+	// Standard lib
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	// Internal
+	"github.com/marksost/img/config"
+	"github.com/marksost/img/image/operations"
+	"github.com/marksost/img/image/utils"
+
+	// Third-party
+	"github.com/kataras/iris"
+)
+
+const (
+	// Path all administrative routes are served under
+	ADMIN_PATH_PREFIX = "/_admin"
+	// Request header containing the token used to access administrative routes
+	// NOTE: A standard "Authorization: Bearer {token}" header is also accepted
+	ADMIN_TOKEN_HEADER = "X-Admin-Token"
+	// URL param used to identify cached images by a path prefix
+	PREFIX_PARAM = "prefix"
+	// URL param used to identify a single cached image by it's path
+	URL_PARAM = "url"
+)
+
+// admin wraps an administrative route's handler, only allowing requests
+// with a valid admin token through to it
+func admin(handler iris.HandlerFunc) iris.HandlerFunc {
+	return func(c *iris.Context) {
+		// Get configured token
+		token := config.GetInstance().Admin.Token
+
+		// Treat administrative routes as non-existent when disabled
+		if token == "" {
+			JSON(c, NotFoundResponse)
+			return
+		}
+
+		// Get token from the request
+		provided := c.RequestHeader(ADMIN_TOKEN_HEADER)
+		if provided == "" {
+			provided = strings.TrimPrefix(c.RequestHeader("Authorization"), "Bearer ")
+		}
+
+		// Verify token
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			JSON(c, UnauthorizedResponse)
+			return
+		}
+
+		handler(c)
+	}
+}
+
+// Handles requests to look up a single image in the source cache
+// NOTE: Only source images are cached, so lookups for transformed images
+// (URLs including operations) report on their source image, and report
+// the transformed image itself as not cached
+func cacheLookup(c *iris.Context) {
+	// Get source cache
+	cache, ok := sourceCache(c)
+	if !ok {
+		return
+	}
+
+	// Get cache key from the request
+	key, ok := cacheKey(c, URL_PARAM)
+	if !ok {
+		return
+	}
+
+	// Form output data
+	data := map[string]interface{}{
+		"url":    key,
+		"cached": false,
+	}
+
+	// Add entry information if cached
+	if entry := cache.Get(key); entry != nil {
+		data["cached"] = true
+		data["etag"] = entry.ETag
+		data["fresh"] = cache.IsFresh(entry)
+		data["last-modified"] = entry.LastModified
+		data["mime-type"] = entry.MimeType
+		data["size"] = len(entry.Data)
+		data["stored-at"] = entry.StoredAt
+	}
+
+	// Add transformation information if requested
+	if operations := lookupOperations(c); len(operations) > 0 {
+		data["transformation"] = map[string]interface{}{
+			"cached":     false,
+			"operations": operations,
+		}
+	}
+
+	// Write JSON output
+	JSON(c, &Response{
+		Code:    http.StatusOK,
+		Message: http.StatusText(http.StatusOK),
+		Data:    data,
+	})
+}
+
+// Handles requests to purge one or more images from the source cache,
+// either by an image's path or by a path prefix
+func cachePurge(c *iris.Context) {
+	// Get source cache
+	cache, ok := sourceCache(c)
+	if !ok {
+		return
+	}
+
+	// Store number of purged images
+	purged := 0
+
+	// Purge by a single path or a path prefix
+	if c.URLParam(URL_PARAM) != "" {
+		key, ok := cacheKey(c, URL_PARAM)
+		if !ok {
+			return
+		}
+
+		if cache.Delete(key) {
+			purged = 1
+		}
+	} else {
+		prefix, ok := cacheKey(c, PREFIX_PARAM)
+		if !ok {
+			return
+		}
+
+		purged = cache.DeletePrefix(prefix)
+	}
+
+	// Write JSON output
+	JSON(c, &Response{
+		Code:    http.StatusOK,
+		Message: http.StatusText(http.StatusOK),
+		Data:    map[string]int{"purged": purged},
+	})
+}
+
+// Handles requests for source cache usage statistics
+func cacheStats(c *iris.Context) {
+	// Get source cache
+	cache, ok := sourceCache(c)
+	if !ok {
+		return
+	}
+
+	// Write JSON output
+	JSON(c, &Response{
+		Code:    http.StatusOK,
+		Message: http.StatusText(http.StatusOK),
+		Data:    map[string]interface{}{"sources": cache.Stats()},
+	})
+}
+
+// cacheKey forms a source cache key from an image path passed in
+// a URL param, writing an error response if none can be formed
+func cacheKey(c *iris.Context, param string) (string, bool) {
+	// Form image URL from the param the same way image requests do
+	// NOTE: Cache keys are the URLs images are downloaded from
+	if str := c.URLParam(param); str != "" {
+		if u := utils.NewDownloader(str).Url(); u != nil {
+			// Remove any operations
+			// NOTE: Only source images are cached, so operations never form part of a key
+			u.RawQuery = ""
+
+			return u.String(), true
+		}
+	}
+
+	// Write JSON output
+	JSON(c, &Response{
+		Code:    http.StatusBadRequest,
+		Message: http.StatusText(http.StatusBadRequest),
+		Data:    []string{"A valid `" + param + "` param is required"},
+		Error:   &ErrorDetail{Param: param},
+	})
+
+	return "", false
+}
+
+// lookupOperations returns the names of the operations included in the
+// image URL passed in the URL param, if any
+func lookupOperations(c *iris.Context) []string {
+	if u := utils.NewDownloader(c.URLParam(URL_PARAM)).Url(); u != nil && u.RawQuery != "" {
+		return operations.NewOperationController([]byte(u.RawQuery)).Names()
+	}
+
+	return nil
+}
+
+// sourceCache returns the source image cache, writing an error response
+// if source caching is disabled
+func sourceCache(c *iris.Context) (*utils.SourceCache, bool) {
+	// Get source cache
+	cache := utils.GetSourceCache()
+	if cache == nil {
+		// Write JSON output
+		JSON(c, &Response{
+			Code:    http.StatusNotImplemented,
+			Message: http.StatusText(http.StatusNotImplemented),
+			Data:    []string{"Source caching is disabled"},
+		})
+
+		return nil, false
+	}
+
+	return cache, true
+}
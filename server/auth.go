@@ -0,0 +1,98 @@
+// auth contains all functionality around authenticating image requests
+// with API keys defined in configuration
+package server
+
+import (
+	// Standard lib
+	"crypto/subtle"
+
+	// Internal
+	"github.com/marksost/img/config"
+	"github.com/marksost/img/image"
+
+	// Third-party
+	"github.com/kataras/iris"
+)
+
+const (
+	// Key the verified API key of a request is stored under in the request context
+	API_KEY_CONTEXT_KEY = "api-key"
+	// Request header containing the API key a request is made with
+	API_KEY_HEADER = "X-API-Key"
+	// URL param containing the API key a request is made with
+	// NOTE: Used when the API key header isn't set
+	API_KEY_PARAM = "api-key"
+)
+
+// authenticate is used to verify image requests are made with a valid API key,
+// storing the key and it's policy in the request context for later use
+// NOTE: Authentication is disabled when no keys are configured, and reserved routes,
+// such as health checks, never require a key
+func authenticate(c *iris.Context) {
+	// Get configured keys
+	keys := config.GetInstance().Auth.Keys
+
+	// Skip authentication if disabled or for reserved routes
+	if len(keys) == 0 || reservedRouteFor(c) != nil {
+		c.Next()
+		return
+	}
+
+	// Get key from the request
+	provided := c.RequestHeader(API_KEY_HEADER)
+	if provided == "" {
+		provided = c.URLParam(API_KEY_PARAM)
+	}
+
+	// Verify key
+	policy := lookupKey(keys, provided)
+	if policy == nil {
+		// Count failed attempts against the client's rate limit
+		// NOTE: Keeps clients from guessing keys, or flooding the server
+		// with unauthenticated requests, without limit
+		settings := config.GetInstance().Server.RateLimit
+		if settings.Rate > 0 {
+			if ok, retryAfter := limiter.allow("ip:"+clientIP(c), settings.Rate, settings.Burst); !ok {
+				rateLimited(c, retryAfter)
+				return
+			}
+		}
+
+		// Write JSON output
+		JSON(c, UnauthorizedResponse)
+		return
+	}
+
+	// Store key and policy for later use
+	c.Set(API_KEY_CONTEXT_KEY, provided)
+	c.Set(image.POLICY_CONTEXT_KEY, policy)
+
+	// Go to next middleware
+	c.Next()
+}
+
+// lookupKey returns the policy for a provided API key, or nil if the key isn't valid
+// NOTE: Compares against every key in constant time, so as not to leak valid keys
+// through response timing
+func lookupKey(keys map[string]*config.KeyPolicy, provided string) *config.KeyPolicy {
+	var (
+		// Whether the provided key was found
+		found bool
+		// The policy of the provided key
+		match *config.KeyPolicy
+	)
+
+	// Loop through keys, storing the matching key's policy
+	for key, policy := range keys {
+		if key != "" && subtle.ConstantTimeCompare([]byte(provided), []byte(key)) == 1 {
+			found, match = true, policy
+		}
+	}
+
+	// Treat keys configured without a policy as unrestricted
+	if found && match == nil {
+		match = &config.KeyPolicy{}
+	}
+
+	return match
+}
@@ -0,0 +1,137 @@
+// health contains all health check route functionality, used by orchestrators
+// to determine if the application is alive and ready to serve requests
+package server
+
+import (
+	// Standard lib
+	"net/http"
+	"sync"
+	"time"
+
+	// Internal
+	"github.com/marksost/img/image/mutableimages"
+
+	// Third-party
+	"github.com/kataras/iris"
+)
+
+const (
+	// Path the liveness check is served under
+	HEALTH_PATH = "/healthz"
+	// Path the readiness check is served under
+	READY_PATH = "/readyz"
+	// Status reported for checks that passed
+	CHECK_STATUS_OK = "ok"
+	// Prefix of the status reported for optional checks that failed
+	CHECK_STATUS_OPTIONAL = "optional: "
+	// Duration readiness check results are reused for, so that frequent
+	// probes don't compete with image processing for resources
+	READINESS_CACHE_TTL = 5 * time.Second
+)
+
+type (
+	// Struct representing a single readiness check
+	readinessCheck struct {
+		check    func() error // Function performing the check
+		name     string       // Name to report the check's result under
+		required func() bool  // Function indicating if the check must pass (nil if always required)
+	}
+	// Struct representing the most recent results of all readiness checks
+	readinessResults struct {
+		checked time.Time        // The time the checks were last run
+		errors  map[string]error // Map of check names to their errors, if any
+		mutex   sync.Mutex       // Mutex used to synchronize access to the results
+	}
+)
+
+var (
+	// Slice of checks that must all pass for the application to be ready
+	// NOTE: Sources are downloaded from origins named in each request and cached
+	// in memory, so there are no configured backends to check
+	// NOTE: The WebP command is only needed for every animated PNG in WebP mode,
+	// and is otherwise only used when WebP output is requested
+	readinessChecks = []*readinessCheck{
+		{name: "gif2webp", check: mutableimages.CheckWebp, required: mutableimages.WebpRequired},
+		{name: "gifsicle", check: mutableimages.CheckGif},
+		{name: "libvips", check: mutableimages.CheckStatic},
+	}
+	// Most recent results of all readiness checks
+	readinessCache = &readinessResults{}
+)
+
+// Handles liveness checks
+// NOTE: Only verifies the server can respond, so that missing dependencies
+// mark the application as not ready rather than restarting it
+func healthz(c *iris.Context) {
+	// Write JSON output
+	JSON(c, &Response{
+		Code:    http.StatusOK,
+		Message: http.StatusText(http.StatusOK),
+		Data:    map[string]string{"status": CHECK_STATUS_OK},
+	})
+}
+
+// Handles readiness checks, running each registered check
+// and reporting the result of each
+func readyz(c *iris.Context) {
+	var (
+		// Response code to use
+		code = http.StatusOK
+		// Map of check names to their results
+		results = make(map[string]string)
+	)
+
+	// Fail readiness while draining requests before shutting down
+	if IsDraining() {
+		code = http.StatusServiceUnavailable
+		results["shutdown"] = "Server is draining in-flight requests"
+	}
+
+	// Loop through checks, storing their results
+	errors := readinessCache.run()
+	for _, rc := range readinessChecks {
+		err := errors[rc.name]
+		if err == nil {
+			results[rc.name] = CHECK_STATUS_OK
+			continue
+		}
+
+		// Report optional checks without failing readiness
+		if rc.required != nil && !rc.required() {
+			results[rc.name] = CHECK_STATUS_OPTIONAL + err.Error()
+			continue
+		}
+
+		code = http.StatusServiceUnavailable
+		results[rc.name] = err.Error()
+	}
+
+	// Write JSON output
+	JSON(c, &Response{
+		Code:    code,
+		Message: http.StatusText(code),
+		Data:    results,
+	})
+}
+
+// run returns the results of all readiness checks, only running
+// the checks again once previous results have expired
+func (rr *readinessResults) run() map[string]error {
+	rr.mutex.Lock()
+	defer rr.mutex.Unlock()
+
+	// Check for unexpired results
+	if rr.errors != nil && time.Since(rr.checked) < READINESS_CACHE_TTL {
+		return rr.errors
+	}
+
+	// Loop through checks, storing results
+	rr.errors = make(map[string]error)
+	for _, rc := range readinessChecks {
+		rr.errors[rc.name] = rc.check()
+	}
+
+	rr.checked = time.Now()
+
+	return rr.errors
+}
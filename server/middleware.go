@@ -1,20 +1,45 @@
 package server
 
 import (
+	// Standard lib
+	"crypto/rand"
+	"encoding/hex"
+	"math"
+	"net/http"
+	"sync/atomic"
+	"time"
+
 	// Internal
 	"github.com/marksost/img/config"
+	"github.com/marksost/img/helpers"
+	"github.com/marksost/img/image"
+	"github.com/marksost/img/image/utils"
+	"github.com/marksost/img/metrics"
+	"github.com/marksost/img/tracing"
 
 	// Third-party
+	log "github.com/Sirupsen/logrus"
 	"github.com/iris-contrib/middleware/cors"
-	"github.com/iris-contrib/middleware/logger"
 	"github.com/iris-contrib/middleware/recovery"
 	"github.com/kataras/iris"
 )
 
 // setMiddleWare is used to set up middleware for all requests
 func setMiddleWare() {
-	// Set up logging middleware
-	server.Use(logger.New())
+	// Track in-flight requests
+	server.UseFunc(trackInFlight)
+
+	// Assign an ID to each request
+	server.UseFunc(assignRequestID)
+
+	// Trace each request
+	server.UseFunc(traceRequest)
+
+	// Log each request once processed
+	server.UseFunc(logAccess)
+
+	// Record request metrics
+	server.UseFunc(recordRequestMetrics)
 
 	// Set up CORS
 	server.Use(cors.Default())
@@ -25,10 +50,213 @@ func setMiddleWare() {
 	// Add pre-processing response headers
 	server.UseFunc(addPreflightResponseHeaders)
 
+	// Authenticate requests with API keys
+	// NOTE: Failed attempts are counted against the client's rate limit
+	trustedProxies = parseTrustedProxies(config.GetInstance().Server.RateLimit.TrustedProxies)
+	server.UseFunc(authenticate)
+
+	// Limit the rate of requests per client
+	server.UseFunc(rateLimit)
+
 	// Add post-processing response headers
 	server.DoneFunc(addPostflightResponseHeaders)
 }
 
+const (
+	// Max length of request IDs accepted from clients
+	MAX_REQUEST_ID_LENGTH = 128
+	// Method requests are recorded under in metrics when not a standard HTTP method
+	// NOTE: Keeps clients from creating unlimited metric series with arbitrary methods
+	METRIC_METHOD_OTHER = "other"
+)
+
+var (
+	// Metrics recorded for all requests
+	requestsTotal = metrics.NewCounter(
+		"img_http_requests_total",
+		"Number of HTTP requests handled, by method and response status code.",
+		"method", "code",
+	)
+	rateLimitedTotal = metrics.NewCounter(
+		"img_rate_limited_requests_total",
+		"Number of requests rejected for exceeding a client's rate limit.",
+	)
+)
+
+// trackInFlight is used to count requests currently being processed,
+// so that they can be drained when shutting down
+func trackInFlight(c *iris.Context) {
+	// Track request until processed
+	atomic.AddInt64(&inFlight, 1)
+	defer atomic.AddInt64(&inFlight, -1)
+
+	// Ask clients to reconnect elsewhere while draining
+	if IsDraining() {
+		c.SetHeader("Connection", "close")
+	}
+
+	// Go to next middleware
+	c.Next()
+}
+
+// assignRequestID is used to assign an ID to each request, using the ID passed in the
+// request's X-Request-ID header when valid, or generating a new one otherwise
+// NOTE: The ID is returned in the response headers and added to all logs for the request
+func assignRequestID(c *iris.Context) {
+	// Get ID from the request, generating one if needed
+	id := c.RequestHeader(utils.HEADER_REQUEST_ID)
+	if !isValidRequestID(id) {
+		id = newRequestID()
+	}
+
+	// Store ID and a logger for the request
+	c.Set(image.REQUEST_ID_CONTEXT_KEY, id)
+	c.Set(image.LOGGER_CONTEXT_KEY, log.WithField("request-id", id))
+
+	// Return ID to the client
+	c.SetHeader(utils.HEADER_REQUEST_ID, id)
+
+	// Go to next middleware
+	c.Next()
+}
+
+// traceRequest is used to trace each request with a root span, continuing the trace
+// passed in the request's traceparent header when valid
+// NOTE: The span is ended *after* processing the request
+func traceRequest(c *iris.Context) {
+	// Start span, skipping tracing if disabled
+	span := tracing.StartRootSpan(string(c.Method())+" request", c.RequestHeader(tracing.TRACEPARENT_HEADER))
+	if span == nil {
+		c.Next()
+		return
+	}
+
+	// Store span and add it's trace to the request's logs
+	c.Set(image.SPAN_CONTEXT_KEY, span)
+	image.AddLogFields(c, log.Fields{"trace-id": span.TraceID()})
+
+	// Go to next middleware
+	c.Next()
+
+	// End span
+	span.SetAttribute("http.method", string(c.Method()))
+	span.SetAttribute("http.path", string(c.Path()))
+	span.SetAttribute("http.status_code", c.Response.StatusCode())
+	span.SetAttribute("request-id", image.RequestID(c))
+	span.End()
+}
+
+// logAccess is used to write a single structured access log for each request,
+// including any details added to the request's logger while processing it
+// NOTE: The log is written *after* processing the request
+func logAccess(c *iris.Context) {
+	// Time request
+	start := time.Now()
+
+	// Go to next middleware
+	c.Next()
+
+	// Write log
+	image.Logger(c).WithFields(log.Fields{
+		"bytes":       len(c.Response.Body()),
+		"duration-ms": helpers.Float642String(time.Since(start).Seconds() * 1000),
+		"method":      string(c.Method()),
+		"path":        string(c.Path()),
+		"remote-ip":   clientIP(c),
+		"status":      c.Response.StatusCode(),
+		"user-agent":  string(c.UserAgent()),
+	}).Info("Request handled")
+}
+
+// recordRequestMetrics is used to record metrics for requests
+// NOTE: Metrics are recorded *after* processing the request
+func recordRequestMetrics(c *iris.Context) {
+	// Go to next middleware
+	c.Next()
+
+	// Record request
+	requestsTotal.Inc(metricMethod(string(c.Method())), helpers.Int2String(c.Response.StatusCode()))
+}
+
+// rateLimit is used to limit the rate of image requests each client can make
+// NOTE: Reserved routes, such as health checks, are never limited
+func rateLimit(c *iris.Context) {
+	// Get rate limit settings
+	settings := config.GetInstance().Server.RateLimit
+	rate, burst := settings.Rate, settings.Burst
+
+	// Use API key's limits when set
+	if policy, ok := c.Get(image.POLICY_CONTEXT_KEY).(*config.KeyPolicy); ok && policy.RateLimit.Rate > 0 {
+		rate = policy.RateLimit.Rate
+		if policy.RateLimit.Burst > 0 {
+			burst = policy.RateLimit.Burst
+		}
+	}
+
+	// Skip limiting if disabled or for reserved routes
+	if rate <= 0 || reservedRouteFor(c) != nil {
+		c.Next()
+		return
+	}
+
+	// Check client is within their limit
+	if ok, retryAfter := limiter.allow(clientKey(c), rate, burst); !ok {
+		rateLimited(c, retryAfter)
+		return
+	}
+
+	// Go to next middleware
+	c.Next()
+}
+
+// rateLimited writes the response for a request rejected for exceeding a client's rate limit
+func rateLimited(c *iris.Context, retryAfter time.Duration) {
+	// Record limited request
+	rateLimitedTotal.Inc()
+
+	// Tell client when to retry, rounding up to the nearest second
+	c.SetHeader("Retry-After", helpers.Int2String(int(math.Ceil(retryAfter.Seconds()))))
+
+	// Write JSON output
+	JSON(c, TooManyRequestsResponse)
+}
+
+// metricMethod returns the method a request should be recorded under in metrics
+func metricMethod(method string) string {
+	switch method {
+	case http.MethodConnect, http.MethodDelete, http.MethodGet, http.MethodHead,
+		http.MethodOptions, http.MethodPatch, http.MethodPost, http.MethodPut, http.MethodTrace:
+		return method
+	}
+
+	return METRIC_METHOD_OTHER
+}
+
+// isValidRequestID returns a boolean indicating if a client-provided request ID
+// is safe to use, only allowing short IDs made up of a limited set of characters
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > MAX_REQUEST_ID_LENGTH {
+		return false
+	}
+
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' ||
+			r == '-' || r == '_' || r == '.' || r == ':') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// newRequestID generates a new random request ID
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
 // addPreflightResponseHeaders is used to add common response headers to requests
 // NOTE: These headers are added *before* processing the request
 func addPreflightResponseHeaders(c *iris.Context) {
@@ -0,0 +1,25 @@
+// Tests the middleware.go file
+package server
+
+import (
+	// Third-party
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("middleware.go", func() {
+	Describe("`metricMethod` method", func() {
+		It("Returns standard HTTP methods unchanged", func() {
+			// Verify return values
+			Expect(metricMethod("GET")).To(Equal("GET"))
+			Expect(metricMethod("DELETE")).To(Equal("DELETE"))
+		})
+
+		It("Returns a fixed value for any other method", func() {
+			// Verify return values
+			Expect(metricMethod("FOO")).To(Equal(METRIC_METHOD_OTHER))
+			Expect(metricMethod("get")).To(Equal(METRIC_METHOD_OTHER))
+			Expect(metricMethod("")).To(Equal(METRIC_METHOD_OTHER))
+		})
+	})
+})
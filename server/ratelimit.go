@@ -0,0 +1,193 @@
+// ratelimit contains all functionality around limiting the rate of requests
+// each client can make, using a token bucket per client
+package server
+
+import (
+	// Standard lib
+	"math"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	// Third-party
+	log "github.com/Sirupsen/logrus"
+	"github.com/kataras/iris"
+)
+
+const (
+	// Interval at which idle token buckets are removed
+	RATE_LIMIT_SWEEP_INTERVAL = time.Minute
+)
+
+type (
+	// Struct representing a rate limiter holding a token bucket per client
+	rateLimiter struct {
+		buckets   map[string]*tokenBucket // Map of client keys to their token buckets
+		lastSweep time.Time               // The last time idle buckets were removed
+		mutex     sync.Mutex              // Mutex used to synchronize access to the buckets
+	}
+	// Struct representing a single client's token bucket
+	tokenBucket struct {
+		burst   int       // Max number of tokens the bucket can hold
+		rate    int       // Number of tokens added to the bucket per second
+		tokens  float64   // The number of requests the client can currently make
+		updated time.Time // The last time the bucket was refilled
+	}
+)
+
+var (
+	// Rate limiter shared by all requests
+	limiter = newRateLimiter()
+	// Slice of networks whose X-Forwarded-For headers are trusted
+	trustedProxies []*net.IPNet
+)
+
+// newRateLimiter creates a new `rateLimiter` and returns it
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{
+		buckets:   make(map[string]*tokenBucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// allow takes a token from a client's bucket, returning a boolean indicating if the
+// request is allowed and, if not, how long until a token will be available
+func (rl *rateLimiter) allow(key string, rate, burst int) (bool, time.Duration) {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	// Always allow at least one request at once
+	if burst < 1 {
+		burst = 1
+	}
+
+	// Remove idle buckets if needed
+	now := time.Now()
+	if now.Sub(rl.lastSweep) > RATE_LIMIT_SWEEP_INTERVAL {
+		rl.sweep(now)
+	}
+
+	// Get or create bucket, starting full
+	b, ok := rl.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: float64(burst), updated: now}
+		rl.buckets[key] = b
+	}
+
+	// Store limits used to refill the bucket
+	b.burst, b.rate = burst, rate
+
+	// Refill bucket based on time passed
+	b.tokens = math.Min(float64(burst), b.tokens+now.Sub(b.updated).Seconds()*float64(rate))
+	b.updated = now
+
+	// Check for an available token
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / float64(rate) * float64(time.Second))
+	}
+
+	b.tokens--
+
+	return true, 0
+}
+
+// sweep removes buckets that have been idle long enough to have refilled,
+// as they're equivalent to new buckets
+// NOTE: Expects the limiter's mutex to be held by the caller
+func (rl *rateLimiter) sweep(now time.Time) {
+	for key, b := range rl.buckets {
+		// Time needed for an empty bucket to refill
+		refill := time.Duration(float64(b.burst) / float64(b.rate) * float64(time.Second))
+
+		if now.Sub(b.updated) > refill {
+			delete(rl.buckets, key)
+		}
+	}
+
+	rl.lastSweep = now
+}
+
+// clientKey returns the key a request should be rate limited by,
+// using a verified API key when available, or the client's IP otherwise
+// NOTE: Only verified keys are used, so that clients can't avoid limits
+// by rotating unverified keys
+func clientKey(c *iris.Context) string {
+	if key, ok := c.Get(API_KEY_CONTEXT_KEY).(string); ok && key != "" {
+		return "key:" + key
+	}
+
+	return "ip:" + clientIP(c)
+}
+
+// clientIP returns the IP of the client that made a request. When the request
+// was made through trusted proxies, the X-Forwarded-For header is walked from
+// right to left to find the first untrusted address
+func clientIP(c *iris.Context) string {
+	// Get IP of the direct peer
+	ip := c.RemoteIP()
+	if !isTrustedProxy(ip) {
+		return ip.String()
+	}
+
+	// Walk forwarded addresses from nearest to furthest
+	hops := strings.Split(string(c.Request.Header.Peek("X-Forwarded-For")), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(hops[i]))
+		if hop == nil {
+			break
+		}
+
+		ip = hop
+		if !isTrustedProxy(hop) {
+			break
+		}
+	}
+
+	return ip.String()
+}
+
+// isTrustedProxy returns a boolean indicating if an IP belongs to a trusted proxy
+func isTrustedProxy(ip net.IP) bool {
+	for _, network := range trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseTrustedProxies parses a comma-separated list of IPs and CIDR ranges
+// into networks, skipping any invalid entries
+func parseTrustedProxies(str string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0)
+
+	for _, entry := range strings.Split(str, ",") {
+		// Skip empty entries
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		// Convert single IPs to CIDR ranges
+		if !strings.Contains(entry, "/") {
+			if ip := net.ParseIP(entry); ip != nil && ip.To4() != nil {
+				entry += "/32"
+			} else {
+				entry += "/128"
+			}
+		}
+
+		// Parse range
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			log.WithField("entry", entry).Warn("Skipping invalid trusted proxy")
+			continue
+		}
+
+		networks = append(networks, network)
+	}
+
+	return networks
+}
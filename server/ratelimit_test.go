@@ -0,0 +1,216 @@
+// Tests the ratelimit.go file
+package server
+
+import (
+	// Standard lib
+	"net"
+	"time"
+
+	// Third-party
+	"github.com/kataras/iris"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/valyala/fasthttp"
+)
+
+var _ = Describe("ratelimit.go", func() {
+	var (
+		// Mock rate limiter to test
+		rl *rateLimiter
+	)
+
+	BeforeEach(func() {
+		// Create mock rate limiter
+		rl = newRateLimiter()
+	})
+
+	Describe("`allow` method", func() {
+		Context("With tokens left in the bucket", func() {
+			It("Allows requests until the burst is used up", func() {
+				// Verify burst requests are allowed
+				for i := 0; i < 3; i++ {
+					allowed, wait := rl.allow("foo", 1, 3)
+					Expect(allowed).To(BeTrue())
+					Expect(wait).To(BeZero())
+				}
+
+				// Verify next request is limited
+				allowed, _ := rl.allow("foo", 1, 3)
+				Expect(allowed).To(BeFalse())
+
+				// Verify other clients have their own buckets
+				allowed, _ = rl.allow("bar", 1, 3)
+				Expect(allowed).To(BeTrue())
+			})
+		})
+
+		Context("With an empty bucket", func() {
+			BeforeEach(func() {
+				// Use up burst
+				rl.allow("foo", 1, 1)
+			})
+
+			It("Returns how long until a token is available", func() {
+				// Call method
+				allowed, wait := rl.allow("foo", 1, 1)
+
+				// Verify return values
+				Expect(allowed).To(BeFalse())
+				Expect(wait).To(BeNumerically(">", 900*time.Millisecond))
+				Expect(wait).To(BeNumerically("<=", time.Second))
+			})
+
+			It("Refills the bucket over time", func() {
+				// Use a high rate so the bucket refills quickly
+				allowed, _ := rl.allow("bar", 100, 1)
+				Expect(allowed).To(BeTrue())
+
+				allowed, _ = rl.allow("bar", 100, 1)
+				Expect(allowed).To(BeFalse())
+
+				// Verify a token is added after waiting
+				time.Sleep(20 * time.Millisecond)
+
+				allowed, _ = rl.allow("bar", 100, 1)
+				Expect(allowed).To(BeTrue())
+			})
+		})
+
+		Context("With a burst below one", func() {
+			It("Allows a single request at once", func() {
+				// Verify return values
+				allowed, _ := rl.allow("foo", 1, 0)
+				Expect(allowed).To(BeTrue())
+
+				allowed, _ = rl.allow("foo", 1, 0)
+				Expect(allowed).To(BeFalse())
+			})
+		})
+	})
+
+	Describe("`sweep` method", func() {
+		BeforeEach(func() {
+			// Create buckets that take one and ten seconds to refill
+			rl.allow("fast", 10, 10)
+			rl.allow("slow", 1, 10)
+		})
+
+		It("Removes buckets that have been idle long enough to refill", func() {
+			// Call method
+			now := time.Now().Add(5 * time.Second)
+			rl.sweep(now)
+
+			// Verify buckets
+			Expect(rl.buckets).To(Not(HaveKey("fast")))
+			Expect(rl.buckets).To(HaveKey("slow"))
+			Expect(rl.lastSweep).To(Equal(now))
+		})
+	})
+
+	Describe("`clientIP` method", func() {
+		// newContext creates a mock context for a request from a peer
+		// with an optional X-Forwarded-For header
+		newContext := func(peer, forwardedFor string) *iris.Context {
+			req := &fasthttp.Request{}
+			if forwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", forwardedFor)
+			}
+
+			ctx := &fasthttp.RequestCtx{}
+			ctx.Init(req, &net.TCPAddr{IP: net.ParseIP(peer)}, nil)
+
+			return &iris.Context{RequestCtx: ctx}
+		}
+
+		BeforeEach(func() {
+			// Set trusted proxies
+			trustedProxies = parseTrustedProxies("10.0.0.0/8, fd00::1")
+		})
+
+		AfterEach(func() {
+			// Reset trusted proxies
+			trustedProxies = nil
+		})
+
+		Context("With a request from an untrusted peer", func() {
+			It("Ignores the X-Forwarded-For header", func() {
+				// Call method
+				ip := clientIP(newContext("203.0.113.5", "198.51.100.7"))
+
+				// Verify return value
+				Expect(ip).To(Equal("203.0.113.5"))
+			})
+		})
+
+		Context("With a request from a trusted peer", func() {
+			It("Returns the first untrusted address, walking from right to left", func() {
+				// Call method
+				ip := clientIP(newContext("10.0.0.1", "198.51.100.7, 203.0.113.9, 10.0.0.2"))
+
+				// Verify return value
+				// NOTE: The left-most address could be spoofed by the client
+				Expect(ip).To(Equal("203.0.113.9"))
+			})
+
+			It("Stops walking at invalid addresses", func() {
+				// Call method
+				ip := clientIP(newContext("10.0.0.1", "198.51.100.7, not-an-ip, 10.0.0.2"))
+
+				// Verify return value
+				Expect(ip).To(Equal("10.0.0.2"))
+			})
+
+			It("Returns the peer without an X-Forwarded-For header", func() {
+				// Call method
+				ip := clientIP(newContext("10.0.0.1", ""))
+
+				// Verify return value
+				Expect(ip).To(Equal("10.0.0.1"))
+			})
+
+			It("Walks IPv6 addresses", func() {
+				// Call method
+				ip := clientIP(newContext("fd00::1", "2001:db8::5"))
+
+				// Verify return value
+				Expect(ip).To(Equal("2001:db8::5"))
+			})
+		})
+	})
+
+	Describe("`isTrustedProxy` method", func() {
+		BeforeEach(func() {
+			// Set trusted proxies
+			trustedProxies = parseTrustedProxies("10.0.0.0/8")
+		})
+
+		AfterEach(func() {
+			// Reset trusted proxies
+			trustedProxies = nil
+		})
+
+		It("Returns a boolean indicating if an IP is within a trusted network", func() {
+			// Verify return values
+			Expect(isTrustedProxy(net.ParseIP("10.1.2.3"))).To(BeTrue())
+			Expect(isTrustedProxy(net.ParseIP("11.1.2.3"))).To(BeFalse())
+		})
+	})
+
+	Describe("`parseTrustedProxies` method", func() {
+		It("Parses IPs and CIDR ranges, skipping invalid entries", func() {
+			// Call method
+			networks := parseTrustedProxies("10.0.0.1, 192.168.0.0/16,, ::1, foo, 10.0.0.0/99")
+
+			// Verify return value
+			Expect(networks).To(HaveLen(3))
+			Expect(networks[0].String()).To(Equal("10.0.0.1/32"))
+			Expect(networks[1].String()).To(Equal("192.168.0.0/16"))
+			Expect(networks[2].String()).To(Equal("::1/128"))
+		})
+
+		It("Returns no networks for an empty list", func() {
+			// Verify return value
+			Expect(parseTrustedProxies("")).To(BeEmpty())
+		})
+	})
+})
@@ -6,21 +6,34 @@ import (
 
 	// Internal
 	"github.com/marksost/img/config"
+	"github.com/marksost/img/image"
 
 	// Third-party
 	"github.com/kataras/iris"
 )
 
-// Response is a struct defining the default shape of JSON responses this application
-// should use when sending responses to HTTP requests
-type Response struct {
-	// HTTP status code
-	Code int `json:"code"`
-	// HTTP status message
-	Message string `json:"message"`
-	// Output data for the request
-	Data interface{} `json:"data"`
-}
+type (
+	// Response is a struct defining the default shape of JSON responses this application
+	// should use when sending responses to HTTP requests
+	Response struct {
+		// HTTP status code
+		Code int `json:"code"`
+		// HTTP status message
+		Message string `json:"message"`
+		// Output data for the request
+		Data interface{} `json:"data"`
+		// Machine-readable information about the error that occurred, if any
+		Error *ErrorDetail `json:"error,omitempty"`
+	}
+	// ErrorDetail is a struct defining machine-readable information about an error,
+	// so that clients can react to errors programmatically
+	ErrorDetail struct {
+		// A stable code describing the error (ex: "source_not_found")
+		Code string `json:"code"`
+		// The name of the request parameter that caused the error, if any
+		Param string `json:"param,omitempty"`
+	}
+)
 
 var (
 	// Common statuc code responses
@@ -56,28 +69,45 @@ var (
 		Code:    http.StatusInternalServerError,
 		Message: http.StatusText(http.StatusInternalServerError),
 	}
+	TooManyRequestsResponse = &Response{ // 429
+		Code:    http.StatusTooManyRequests,
+		Message: http.StatusText(http.StatusTooManyRequests),
+	}
 	UnauthorizedResponse = &Response{ // 401
 		Code:    http.StatusUnauthorized,
 		Message: http.StatusText(http.StatusUnauthorized),
 	}
 )
 
-// JSON outputs a JSON response via the server for a number of scenarios:
+// JSON outputs a JSON response via the server. Error responses always include
+// machine-readable error details, but their data is only output:
 // If the environment the app is runnning is ~not~ production
-// If a non-error (i.e. 200) response is detected
 // If a "debug" param is passed with the request
-// Otherwise, an empty text response with the proper code is output
+// Otherwise, data is omitted so as not to expose internal details
 func JSON(c *iris.Context, resp *Response) {
-	// Check if this is not a production environment, or a debug flag was enabled,
-	// or the status code is a non-error
-	if !config.GetInstance().IsProduction() ||
-		c.URLParam(DEBUG_PARAM) == "true" ||
-		resp.Code == http.StatusOK {
-		// Output JSON
+	// Output non-error responses as-is
+	if resp.Code < http.StatusBadRequest {
 		c.JSON(resp.Code, resp)
 		return
 	}
 
-	// Output empty response as text
-	c.Text(resp.Code, "")
+	// Copy response, so shared responses aren't modified
+	out := *resp
+
+	// Add default error details if needed
+	if out.Error == nil {
+		out.Error = &ErrorDetail{}
+	}
+
+	if out.Error.Code == "" {
+		out.Error = &ErrorDetail{Code: image.DefaultErrorCode(out.Code), Param: out.Error.Param}
+	}
+
+	// Omit data in production, unless a debug flag was enabled
+	if config.GetInstance().IsProduction() && c.URLParam(DEBUG_PARAM) != "true" {
+		out.Data = nil
+	}
+
+	// Output JSON
+	c.JSON(out.Code, &out)
 }
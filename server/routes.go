@@ -3,32 +3,150 @@ package server
 import (
 	// Standard lib
 	"net/http"
+	"strings"
 
 	// Internal
+	"github.com/marksost/img/config"
+	"github.com/marksost/img/helpers"
 	"github.com/marksost/img/image"
+	"github.com/marksost/img/metrics"
 
 	// Third-party
 	"github.com/kataras/iris"
 )
 
+const (
+	// URL param used to request metadata about an image instead of the image itself
+	INFO_PARAM = "info"
+	// Path application metrics are served under
+	METRICS_PATH = "/metrics"
+)
+
+type (
+	// Struct representing a route served ahead of the catch-all image route
+	// NOTE: Needed, since the system uses catch-all params, meaning no other
+	// routes can be registered with the server alongside them
+	reservedRoute struct {
+		handler iris.HandlerFunc // The handler to serve the route with
+		method  string           // The HTTP method of the route
+		path    string           // The path of the route
+	}
+)
+
+var (
+	// Slice of routes served ahead of the catch-all image route
+	reservedRoutes []*reservedRoute
+)
+
 func setRoutes() {
+	// Favicon route
+	reserve(http.MethodGet, "/favicon.ico", favIcon)
+
+	// Health check routes
+	reserve(http.MethodGet, HEALTH_PATH, healthz)
+	reserve(http.MethodGet, READY_PATH, readyz)
+
+	// Metrics route
+	reserve(http.MethodGet, METRICS_PATH, exportMetrics)
+
+	// Admin routes
+	reserve(http.MethodGet, ADMIN_PATH_PREFIX+"/cache/lookup", admin(cacheLookup))
+	reserve(http.MethodPost, ADMIN_PATH_PREFIX+"/cache/purge", admin(cachePurge))
+	reserve(http.MethodGet, ADMIN_PATH_PREFIX+"/cache/stats", admin(cacheStats))
+
 	// Main image handling route
-	server.Get("/*img", img)
+	server.Get("/*img", dispatch(img))
 
 	// HEAD requests
-	server.Head("/*img", ok)
+	// NOTE: Handled the same as GET requests, with the server omitting the body
+	// while keeping all headers, including the Content-Length of the body
+	server.Head("/*img", dispatch(img))
 
 	// Dis-allowed routes/methods
-	server.Post("/*img", methodNotAllowed)
-	server.Put("/*img", methodNotAllowed)
-	server.Patch("/*img", methodNotAllowed)
-	server.Delete("/*img", methodNotAllowed)
+	server.Post("/*img", dispatch(methodNotAllowed))
+	server.Put("/*img", dispatch(methodNotAllowed))
+	server.Patch("/*img", dispatch(methodNotAllowed))
+	server.Delete("/*img", dispatch(methodNotAllowed))
 
 	// Handle errors
 	server.OnError(NotFoundResponse.Code, notFound)
 	server.OnError(ServerErrorResponse.Code, serverError)
 }
 
+// reserve registers a route to be served ahead of the catch-all image route
+func reserve(method, path string, handler iris.HandlerFunc) {
+	reservedRoutes = append(reservedRoutes, &reservedRoute{
+		handler: handler,
+		method:  method,
+		path:    path,
+	})
+}
+
+// dispatch wraps a catch-all route's handler, serving any matching reserved route
+// in place of the handler
+func dispatch(handler iris.HandlerFunc) iris.HandlerFunc {
+	return func(c *iris.Context) {
+		// Serve matching reserved route if found
+		if route := reservedRouteFor(c); route != nil {
+			route.handler(c)
+			return
+		}
+
+		// Reject other methods for reserved paths, and unknown administrative paths
+		// NOTE: Keeps them from being handled as image requests
+		path := c.Param("img")
+		if isReservedPath(path) {
+			JSON(c, MethodNotAllowedResponse)
+			return
+		}
+
+		if strings.HasPrefix(path, ADMIN_PATH_PREFIX+"/") {
+			JSON(c, NotFoundResponse)
+			return
+		}
+
+		handler(c)
+	}
+}
+
+// isReservedPath returns a boolean indicating if any reserved route uses a path
+func isReservedPath(path string) bool {
+	for _, route := range reservedRoutes {
+		if route.path == path {
+			return true
+		}
+	}
+
+	return false
+}
+
+// reservedRouteFor returns the reserved route matching a request, or nil if none match
+// NOTE: HEAD requests match reserved GET routes
+func reservedRouteFor(c *iris.Context) *reservedRoute {
+	// Store method and path of the request
+	method, path := string(c.Method()), c.Param("img")
+	if method == http.MethodHead {
+		method = http.MethodGet
+	}
+
+	// Loop through reserved routes, returning the first match
+	for _, route := range reservedRoutes {
+		if route.method == method && route.path == path {
+			return route
+		}
+	}
+
+	return nil
+}
+
+// Handles GET requests for application metrics
+func exportMetrics(c *iris.Context) {
+	// Write metrics in the Prometheus text exposition format
+	c.SetStatusCode(http.StatusOK)
+	c.SetContentType(metrics.CONTENT_TYPE)
+	c.SetBodyString(metrics.Export())
+}
+
 // Handles GET requests for a favicon
 func favIcon(c *iris.Context) {
 	// Serve file directly
@@ -38,32 +156,64 @@ func favIcon(c *iris.Context) {
 // Handles all GET requests to the application
 // not matching any other route rules
 func img(c *iris.Context) {
-	// Check for favicon as the request
-	// NOTE: Needed, since the system uses catch-all params
-	if c.Param("img") == "/favicon.ico" {
-		favIcon(c)
-		return
-	}
-
 	// Form new image
 	i := image.NewImage(c)
 
+	// Write image metadata if requested
+	if c.URLParam(INFO_PARAM) == "true" {
+		info(c, i)
+		return
+	}
+
 	// Process request
 	if err := i.Process(); err != nil {
-		// Store error code
-		code := err.(*image.ImageRequestError).Code()
-
-		// Write JSON output
-		JSON(c, &Response{
-			Code:    code,
-			Message: http.StatusText(code),
-			Data:    []string{err.Error()},
-		})
+		imageError(c, err)
+		return
+	}
+
+	// Write output based on mime type, tracing the write
+	// NOTE: Placeholder images may be served with an error status code
+	span := image.StartSpan(c, "write response")
+	span.SetAttribute("bytes", len(i.Data()))
+	span.SetError(c.RenderWithStatus(i.Status(), i.OutputMimeType(), i.Data()))
+	span.End()
+}
+
+// Handles requests for metadata about an image
+func info(c *iris.Context, i *image.Image) {
+	// Read image metadata
+	data, err := i.Info()
+	if err != nil {
+		imageError(c, err)
 		return
 	}
 
-	// Write output based on mime type
-	c.Render(i.MimeType(), i.Data())
+	// Write JSON output
+	JSON(c, &Response{
+		Code:    http.StatusOK,
+		Message: http.StatusText(http.StatusOK),
+		Data:    data,
+	})
+}
+
+// imageError writes the response for an error that occurred while handling an image request
+func imageError(c *iris.Context, err error) {
+	// Store error and it's code
+	ire := err.(*image.ImageRequestError)
+	code := ire.Code()
+
+	// Ask clients to retry later when processing is over capacity
+	if code == http.StatusServiceUnavailable {
+		c.SetHeader("Retry-After", helpers.Int2String(config.GetInstance().Images.Concurrency.RetryAfter))
+	}
+
+	// Write JSON output
+	JSON(c, &Response{
+		Code:    code,
+		Message: http.StatusText(code),
+		Data:    []string{err.Error()},
+		Error:   &ErrorDetail{Code: ire.ErrorCode(), Param: ire.Param()},
+	})
 }
 
 // Handles all dis-allowed routes
@@ -78,12 +228,6 @@ func notFound(c *iris.Context) {
 	JSON(c, NotFoundResponse)
 }
 
-// Handles all routes server 200 OK statuses
-func ok(c *iris.Context) {
-	// Write JSON output
-	JSON(c, OKResponse)
-}
-
 // Handles all 500 server errors
 func serverError(c *iris.Context) {
 	// Write JSON output
@@ -14,8 +14,8 @@ import (
 
 // setSerializers is used to set custom Iris serializers used throughout the application
 func setSerializers() {
-	// Loop through image MIME types, setting up a serializer for each
-	for mime, _ := range utils.MimeTypes {
+	// Loop through image output MIME types, setting up a serializer for each
+	for _, mime := range utils.OutputMimeTypes {
 		server.UseSerializer(mime, serializer.SerializeFunc(imgSerializer))
 	}
 }
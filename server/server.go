@@ -2,11 +2,13 @@ package server
 
 import (
 	// Standard lib
+	"sync/atomic"
 	"time"
 
 	// Internal
 	"github.com/marksost/img/config"
 	"github.com/marksost/img/helpers"
+	"github.com/marksost/img/image"
 
 	// Third-party
 	log "github.com/Sirupsen/logrus"
@@ -14,13 +16,21 @@ import (
 )
 
 const (
+	// Interval at which in-flight requests are checked while draining
+	DRAIN_POLL_INTERVAL = 50 * time.Millisecond
 	// URL Param used to indicate a debug request
-	DEBUG_PARAM = "debug"
+	DEBUG_PARAM = image.DEBUG_PARAM
 	// Key to store response headers under in the request context
 	RESPONSE_HEADERS_KEY = "response-headers"
 )
 
 var (
+	// Flag indicating the server is draining in-flight requests before shutting down
+	// NOTE: Accessed atomically, where a non-zero value means draining
+	draining int32
+	// Number of requests currently being processed
+	// NOTE: Accessed atomically
+	inFlight int64
 	// Server for all requests
 	server *iris.Framework
 )
@@ -47,14 +57,56 @@ func Start() {
 	go server.Listen(":" + helpers.Int2String(c.Server.Port))
 }
 
+// Stop gracefully shuts the server down. Readiness checks start failing while connections
+// are still accepted for the configured drain delay, then the listener is closed so no new
+// connections are accepted, and in-flight requests are given until the configured shutdown
+// timeout to finish
 func Stop() {
+	// Get configuration instance
+	c := config.GetInstance()
+
+	// Mark server as draining
+	atomic.StoreInt32(&draining, 1)
+
+	// Keep serving requests while orchestrators observe the failing readiness checks
+	// NOTE: Closing the listener first would leave them unable to reach the checks
+	if delay := time.Duration(c.Server.Timeouts.DrainDelay) * time.Second; delay > 0 {
+		log.WithField("delay", delay.String()).Info("Waiting for readiness checks to be observed before draining")
+		time.Sleep(delay)
+	}
+
+	// Stop accepting new connections
+	closeListener()
+
+	// Wait for in-flight requests to finish, or the deadline to pass
+	deadline := time.Now().Add(time.Duration(c.Server.Timeouts.Shutdown) * time.Second)
+	for atomic.LoadInt64(&inFlight) > 0 {
+		if time.Now().After(deadline) {
+			log.WithField("in-flight", atomic.LoadInt64(&inFlight)).Warn("Shutdown timeout reached with requests still in-flight")
+			return
+		}
+
+		time.Sleep(DRAIN_POLL_INTERVAL)
+	}
+
+	log.Info("All in-flight requests have finished")
+}
+
+// IsDraining returns a boolean indicating if the server is draining
+// in-flight requests before shutting down
+func IsDraining() bool {
+	return atomic.LoadInt32(&draining) != 0
+}
+
+// closeListener closes the server's listener so no new connections are accepted
+func closeListener() {
 	// Catch panics
 	// NOTE: These can occur when the network connection is already closed
 	defer func() {
 		_ = recover()
 	}()
 
-	// Attempt to stop the server
+	// Attempt to close the listener
 	if err := server.Close(); err != nil {
 		log.WithField("error", err.Error()).Warn("Error stopping the server")
 	}
@@ -0,0 +1,251 @@
+// exporters contains all functionality around exporting finished spans
+// in the OTLP/JSON format, either to a collector or to a file
+// See https://opentelemetry.io/docs/specs/otlp/#json-protobuf-encoding for more information
+package tracing
+
+import (
+	// Standard lib
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Path spans are sent to on OTLP/HTTP collectors
+	OTLP_TRACES_PATH = "/v1/traces"
+	// Timeout used when sending spans to a collector
+	OTLP_TIMEOUT = 10 * time.Second
+	// Name of the instrumentation scope spans are exported under
+	SCOPE_NAME = "github.com/marksost/img"
+	// Name of the service spans are exported under
+	SERVICE_NAME = "img"
+
+	// Status code of spans whose operation failed
+	STATUS_CODE_ERROR = 2
+)
+
+type (
+	// Interface all exporters must satisfy to receive finished spans
+	Exporter interface {
+		Export([]*Span) error
+	}
+	// Struct representing an exporter that appends spans to a file,
+	// with each batch written as a single line of OTLP/JSON
+	FileExporter struct {
+		mutex sync.Mutex // Mutex used to synchronize writes to the file
+		path  string     // Path of the file spans are written to
+	}
+	// Struct representing an exporter that sends spans to an OTLP/HTTP collector
+	OTLPExporter struct {
+		client *http.Client // Client used to send spans
+		url    string       // URL spans are sent to
+	}
+
+	// Structs representing the OTLP/JSON encoding of spans
+	otlpAnyValue struct {
+		BoolValue   *bool    `json:"boolValue,omitempty"`
+		DoubleValue *float64 `json:"doubleValue,omitempty"`
+		IntValue    string   `json:"intValue,omitempty"`
+		StringValue *string  `json:"stringValue,omitempty"`
+	}
+	otlpAttribute struct {
+		Key   string       `json:"key"`
+		Value otlpAnyValue `json:"value"`
+	}
+	otlpResourceSpans struct {
+		Resource struct {
+			Attributes []otlpAttribute `json:"attributes"`
+		} `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpScopeSpans struct {
+		Scope struct {
+			Name string `json:"name"`
+		} `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpSpan struct {
+		Attributes        []otlpAttribute `json:"attributes,omitempty"`
+		EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+		Kind              int             `json:"kind"`
+		Name              string          `json:"name"`
+		ParentSpanID      string          `json:"parentSpanId,omitempty"`
+		SpanID            string          `json:"spanId"`
+		StartTimeUnixNano string          `json:"startTimeUnixNano"`
+		Status            otlpStatus      `json:"status"`
+		TraceID           string          `json:"traceId"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code,omitempty"`
+		Message string `json:"message,omitempty"`
+	}
+	otlpTraces struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+)
+
+/* Begin file exporter methods */
+
+// NewFileExporter creates a new `FileExporter` and returns it
+func NewFileExporter(path string) *FileExporter {
+	return &FileExporter{path: path}
+}
+
+// Export appends a batch of spans to the exporter's file
+func (e *FileExporter) Export(spans []*Span) error {
+	// Encode spans
+	data, err := encodeSpans(spans)
+	if err != nil {
+		return err
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	// Open file, creating it if needed
+	f, err := os.OpenFile(e.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	// Write spans as a single line
+	if _, err = f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+/* End file exporter methods */
+
+/* Begin OTLP exporter methods */
+
+// NewOTLPExporter creates a new `OTLPExporter` for a collector endpoint and returns it
+func NewOTLPExporter(endpoint string) *OTLPExporter {
+	return &OTLPExporter{
+		client: &http.Client{Timeout: OTLP_TIMEOUT},
+		url:    strings.TrimRight(endpoint, "/") + OTLP_TRACES_PATH,
+	}
+}
+
+// Export sends a batch of spans to the exporter's collector
+func (e *OTLPExporter) Export(spans []*Span) error {
+	// Encode spans
+	data, err := encodeSpans(spans)
+	if err != nil {
+		return err
+	}
+
+	// Send spans
+	resp, err := e.client.Post(e.url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	// Verify spans were accepted
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("Collector responded with a %d status code", resp.StatusCode)
+	}
+
+	return nil
+}
+
+/* End OTLP exporter methods */
+
+/* Begin encoding methods */
+
+// encodeSpans encodes a batch of spans as an OTLP/JSON traces payload
+func encodeSpans(spans []*Span) ([]byte, error) {
+	// Form scope spans
+	scope := otlpScopeSpans{Spans: make([]otlpSpan, 0, len(spans))}
+	scope.Scope.Name = SCOPE_NAME
+
+	for _, s := range spans {
+		scope.Spans = append(scope.Spans, encodeSpan(s))
+	}
+
+	// Form resource spans
+	resource := otlpResourceSpans{ScopeSpans: []otlpScopeSpans{scope}}
+	resource.Resource.Attributes = []otlpAttribute{encodeAttribute("service.name", SERVICE_NAME)}
+
+	return json.Marshal(otlpTraces{ResourceSpans: []otlpResourceSpans{resource}})
+}
+
+// encodeSpan encodes a single span in the OTLP/JSON format
+func encodeSpan(s *Span) otlpSpan {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	span := otlpSpan{
+		EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+		Kind:              s.kind,
+		Name:              s.name,
+		SpanID:            hex.EncodeToString(s.spanID[:]),
+		StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+		TraceID:           hex.EncodeToString(s.traceID[:]),
+	}
+
+	// Root spans have no parent
+	if s.parentID != [8]byte{} {
+		span.ParentSpanID = hex.EncodeToString(s.parentID[:])
+	}
+
+	// Set error status
+	if s.err != nil {
+		span.Status = otlpStatus{Code: STATUS_CODE_ERROR, Message: s.err.Error()}
+	}
+
+	// Encode attributes, sorted by key for stable output
+	keys := make([]string, 0, len(s.attributes))
+	for key := range s.attributes {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		span.Attributes = append(span.Attributes, encodeAttribute(key, s.attributes[key]))
+	}
+
+	return span
+}
+
+// encodeAttribute encodes a single attribute in the OTLP/JSON format
+// NOTE: Values of unsupported types are encoded as strings
+func encodeAttribute(key string, value interface{}) otlpAttribute {
+	attr := otlpAttribute{Key: key}
+
+	switch v := value.(type) {
+	case bool:
+		attr.Value.BoolValue = &v
+	case float32:
+		f := float64(v)
+		attr.Value.DoubleValue = &f
+	case float64:
+		attr.Value.DoubleValue = &v
+	case int:
+		attr.Value.IntValue = strconv.FormatInt(int64(v), 10)
+	case int64:
+		attr.Value.IntValue = strconv.FormatInt(v, 10)
+	case string:
+		attr.Value.StringValue = &v
+	default:
+		str := fmt.Sprint(v)
+		attr.Value.StringValue = &str
+	}
+
+	return attr
+}
+
+/* End encoding methods */
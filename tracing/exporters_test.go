@@ -0,0 +1,170 @@
+// Tests the exporters.go file
+package tracing
+
+import (
+	// Standard lib
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+
+	// Third-party
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("exporters.go", func() {
+	var (
+		// Mock spans to export
+		parent, child *Span
+	)
+
+	BeforeEach(func() {
+		// Enable tracing with an exporter that's never called
+		SetExporter(&memoryExporter{})
+
+		// Create mock spans
+		parent = StartRootSpan("request", "")
+		parent.SetAttribute("http.status_code", 500)
+		parent.SetError(fmt.Errorf("Error"))
+
+		child = StartSpan(parent, "download", SPAN_KIND_CLIENT)
+		child.SetAttribute("cache.hit", false)
+		child.SetAttribute("url", "http://example.com/image.jpg")
+		child.SetAttribute("duration", 1.5)
+
+		child.End()
+		parent.End()
+	})
+
+	AfterEach(func() {
+		// Disable tracing
+		SetExporter(nil)
+	})
+
+	Describe("`FileExporter` methods", func() {
+		var (
+			// Path of the file to export to
+			path string
+		)
+
+		BeforeEach(func() {
+			f, err := ioutil.TempFile("", "traces")
+			Expect(err).To(Not(HaveOccurred()))
+			f.Close()
+
+			path = f.Name()
+		})
+
+		AfterEach(func() {
+			os.Remove(path)
+		})
+
+		Describe("`Export` method", func() {
+			It("Appends a line of OTLP/JSON per batch of spans", func() {
+				e := NewFileExporter(path)
+
+				// Call method
+				Expect(e.Export([]*Span{child, parent})).To(Succeed())
+				Expect(e.Export([]*Span{parent})).To(Succeed())
+
+				// Read file
+				data, err := ioutil.ReadFile(path)
+				Expect(err).To(Not(HaveOccurred()))
+
+				lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+				Expect(lines).To(HaveLen(2))
+
+				// Verify first batch
+				var traces map[string]interface{}
+				Expect(json.Unmarshal([]byte(lines[0]), &traces)).To(Succeed())
+
+				resource := traces["resourceSpans"].([]interface{})[0].(map[string]interface{})
+				Expect(resource["resource"]).To(Equal(map[string]interface{}{
+					"attributes": []interface{}{map[string]interface{}{
+						"key":   "service.name",
+						"value": map[string]interface{}{"stringValue": SERVICE_NAME},
+					}},
+				}))
+
+				spans := resource["scopeSpans"].([]interface{})[0].(map[string]interface{})["spans"].([]interface{})
+				Expect(spans).To(HaveLen(2))
+
+				// Verify child span
+				span := spans[0].(map[string]interface{})
+				Expect(span["name"]).To(Equal("download"))
+				Expect(span["kind"]).To(Equal(float64(SPAN_KIND_CLIENT)))
+				Expect(span["traceId"]).To(Equal(parent.TraceID()))
+				Expect(span["parentSpanId"]).To(Equal(fmt.Sprintf("%x", parent.spanID)))
+				Expect(span["startTimeUnixNano"]).To(Equal(fmt.Sprint(child.start.UnixNano())))
+				Expect(span["endTimeUnixNano"]).To(Equal(fmt.Sprint(child.end.UnixNano())))
+				Expect(span["status"]).To(Equal(map[string]interface{}{}))
+				Expect(span["attributes"]).To(Equal([]interface{}{
+					map[string]interface{}{"key": "cache.hit", "value": map[string]interface{}{"boolValue": false}},
+					map[string]interface{}{"key": "duration", "value": map[string]interface{}{"doubleValue": 1.5}},
+					map[string]interface{}{"key": "url", "value": map[string]interface{}{"stringValue": "http://example.com/image.jpg"}},
+				}))
+
+				// Verify parent span
+				span = spans[1].(map[string]interface{})
+				Expect(span).To(Not(HaveKey("parentSpanId")))
+				Expect(span["kind"]).To(Equal(float64(SPAN_KIND_SERVER)))
+				Expect(span["status"]).To(Equal(map[string]interface{}{
+					"code":    float64(STATUS_CODE_ERROR),
+					"message": "Error",
+				}))
+				Expect(span["attributes"]).To(Equal([]interface{}{
+					map[string]interface{}{"key": "http.status_code", "value": map[string]interface{}{"intValue": "500"}},
+				}))
+			})
+		})
+	})
+
+	Describe("`OTLPExporter` methods", func() {
+		Describe("`Export` method", func() {
+			var (
+				// Mock collector to export to
+				ts *httptest.Server
+				// Details of the request the collector received
+				path, contentType string
+				body              []byte
+				// Status code the collector responds with
+				status int
+			)
+
+			BeforeEach(func() {
+				status = http.StatusOK
+
+				ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					path, contentType = r.URL.Path, r.Header.Get("Content-Type")
+					body, _ = ioutil.ReadAll(r.Body)
+					w.WriteHeader(status)
+				}))
+			})
+
+			AfterEach(func() {
+				ts.Close()
+			})
+
+			It("Sends spans to the collector", func() {
+				// Call method
+				Expect(NewOTLPExporter(ts.URL + "/").Export([]*Span{parent})).To(Succeed())
+
+				// Verify request
+				Expect(path).To(Equal(OTLP_TRACES_PATH))
+				Expect(contentType).To(Equal("application/json"))
+				Expect(string(body)).To(ContainSubstring(`"traceId":"` + parent.TraceID() + `"`))
+			})
+
+			It("Returns an error when the collector rejects spans", func() {
+				status = http.StatusBadRequest
+
+				// Verify return value
+				Expect(NewOTLPExporter(ts.URL).Export([]*Span{parent})).To(HaveOccurred())
+			})
+		})
+	})
+})
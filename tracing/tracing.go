@@ -0,0 +1,355 @@
+// tracing package defines lightweight, OpenTelemetry-style tracing spans
+// used to time the stages of handling a request, and the batching of finished spans
+// to an exporter. Trace context is propagated using the W3C traceparent format
+// See https://www.w3.org/TR/trace-context/ for more information
+package tracing
+
+import (
+	// Standard lib
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	// Internal
+	"github.com/marksost/img/config"
+
+	// Third-party
+	log "github.com/Sirupsen/logrus"
+)
+
+const (
+	// Names of the supported exporters
+	EXPORTER_FILE = "file"
+	EXPORTER_OTLP = "otlp"
+
+	// Max number of finished spans held before they're exported
+	MAX_PENDING_SPANS = 512
+	// Interval at which finished spans are exported
+	FLUSH_INTERVAL = 5 * time.Second
+
+	// Kinds of spans, matching the values used by OTLP
+	SPAN_KIND_INTERNAL = 1
+	SPAN_KIND_SERVER   = 2
+	SPAN_KIND_CLIENT   = 3
+
+	// Header trace context is propagated with
+	TRACEPARENT_HEADER = "traceparent"
+	// Version of the traceparent format spans are propagated with
+	TRACEPARENT_VERSION = "00"
+	// Flags spans are propagated with
+	// NOTE: All spans are exported, so are always marked as sampled
+	TRACEPARENT_FLAGS = "01"
+)
+
+var (
+	// The exporter finished spans are sent to
+	// NOTE: Tracing is disabled when no exporter is set
+	exporter Exporter
+	// Slice of finished spans waiting to be exported
+	pending []*Span
+	// Mutex used to synchronize access to the exporter and pending spans
+	mutex sync.Mutex
+	// Channel used to stop periodically exporting spans
+	stop chan struct{}
+)
+
+type (
+	// Struct representing a single timed operation within a trace
+	// NOTE: All methods are safe to call on a nil span, which is used
+	// when tracing is disabled
+	Span struct {
+		attributes map[string]interface{} // Map of attributes describing the span
+		end        time.Time              // Time at which the span ended
+		err        error                  // The error the span's operation failed with, if any
+		kind       int                    // The kind of the span
+		mutex      sync.Mutex             // Mutex used to synchronize access to the span
+		name       string                 // Name of the span
+		parentID   [8]byte                // ID of the span's parent, or all zeros for root spans
+		spanID     [8]byte                // ID of the span
+		start      time.Time              // Time at which the span started
+		traceID    [16]byte               // ID of the trace the span belongs to
+	}
+)
+
+/* Begin tracer methods */
+
+// Init sets up the exporter configured for the application,
+// and starts periodically exporting finished spans
+func Init() {
+	// Get configuration instance
+	c := config.GetInstance()
+
+	// Create exporter
+	switch c.Tracing.Exporter {
+	case "":
+		return
+	case EXPORTER_FILE:
+		SetExporter(NewFileExporter(c.Tracing.File))
+	case EXPORTER_OTLP:
+		SetExporter(NewOTLPExporter(c.Tracing.Endpoint))
+	default:
+		log.WithField("exporter", c.Tracing.Exporter).Warn("Unknown tracing exporter, tracing is disabled")
+		return
+	}
+
+	// Export spans periodically
+	stop = make(chan struct{})
+	go func(stop chan struct{}) {
+		ticker := time.NewTicker(FLUSH_INTERVAL)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				Flush()
+			case <-stop:
+				return
+			}
+		}
+	}(stop)
+}
+
+// Shutdown stops periodically exporting spans, and exports any spans
+// that are still waiting to be exported
+func Shutdown() {
+	if stop != nil {
+		close(stop)
+		stop = nil
+	}
+
+	Flush()
+}
+
+// SetExporter sets the exporter finished spans are sent to,
+// or disables tracing if the exporter is nil
+func SetExporter(e Exporter) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	exporter = e
+	pending = nil
+}
+
+// Enabled returns a boolean indicating if spans are being recorded
+func Enabled() bool {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	return exporter != nil
+}
+
+// Flush exports all finished spans that are waiting to be exported
+func Flush() {
+	// Take pending spans
+	mutex.Lock()
+	e, spans := exporter, pending
+	pending = nil
+	mutex.Unlock()
+
+	if e == nil || len(spans) == 0 {
+		return
+	}
+
+	// Export spans, logging failures
+	if err := e.Export(spans); err != nil {
+		log.WithError(err).WithField("spans", len(spans)).Error("Failed to export spans")
+	}
+}
+
+// finish queues a finished span for exporting, exporting all waiting spans
+// once enough have finished
+func finish(s *Span) {
+	mutex.Lock()
+	if exporter == nil {
+		mutex.Unlock()
+		return
+	}
+
+	pending = append(pending, s)
+	full := len(pending) >= MAX_PENDING_SPANS
+	mutex.Unlock()
+
+	if full {
+		go Flush()
+	}
+}
+
+/* End tracer methods */
+
+/* Begin span methods */
+
+// StartRootSpan starts a new server span for an incoming request, continuing
+// the trace of a valid traceparent header if one was sent
+// NOTE: Returns nil if tracing is disabled
+func StartRootSpan(name, traceparent string) *Span {
+	if !Enabled() {
+		return nil
+	}
+
+	s := newSpan(name, SPAN_KIND_SERVER)
+
+	// Continue the caller's trace, or start a new one
+	if traceID, parentID, ok := ParseTraceparent(traceparent); ok {
+		s.traceID, s.parentID = traceID, parentID
+	} else {
+		randomID(s.traceID[:])
+	}
+
+	return s
+}
+
+// StartSpan starts a new span of a given kind as a child of another span
+// NOTE: Returns nil if the parent is nil
+func StartSpan(parent *Span, name string, kind int) *Span {
+	if parent == nil {
+		return nil
+	}
+
+	s := newSpan(name, kind)
+	s.traceID, s.parentID = parent.traceID, parent.spanID
+
+	return s
+}
+
+// End marks the span as finished, queueing it for exporting
+// NOTE: Only the first call has any effect
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+
+	s.mutex.Lock()
+	if !s.end.IsZero() {
+		s.mutex.Unlock()
+		return
+	}
+
+	s.end = time.Now()
+	s.mutex.Unlock()
+
+	finish(s)
+}
+
+// SetAttribute sets an attribute describing the span
+// NOTE: Values should be strings, booleans, integers or floats
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.attributes[key] = value
+}
+
+// SetError marks the span's operation as having failed with an error
+// NOTE: Nil errors are ignored
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.err = err
+}
+
+// TraceID returns the hex-encoded ID of the trace the span belongs to,
+// or an empty string for nil spans
+func (s *Span) TraceID() string {
+	if s == nil {
+		return ""
+	}
+
+	return hex.EncodeToString(s.traceID[:])
+}
+
+// Traceparent returns the traceparent header value used to propagate
+// the span to other services, or an empty string for nil spans
+func (s *Span) Traceparent() string {
+	if s == nil {
+		return ""
+	}
+
+	return fmt.Sprintf("%s-%s-%s-%s", TRACEPARENT_VERSION,
+		hex.EncodeToString(s.traceID[:]), hex.EncodeToString(s.spanID[:]), TRACEPARENT_FLAGS)
+}
+
+/* End span methods */
+
+/* Begin utility methods */
+
+// ParseTraceparent parses a traceparent header value, returning the trace and parent span IDs
+// it contains, and a boolean indicating if the value was valid
+func ParseTraceparent(traceparent string) (traceID [16]byte, parentID [8]byte, ok bool) {
+	// Split value into it's version, trace ID, parent ID and flags
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return
+	}
+
+	// Verify version, allowing extra fields only for future versions
+	if parts[0] == "ff" || (parts[0] == TRACEPARENT_VERSION && len(parts) != 4) {
+		return
+	}
+
+	if _, err := hex.DecodeString(parts[0] + parts[3]); err != nil {
+		return
+	}
+
+	// Decode IDs, which must be lowercase and not all zeros
+	if strings.ToLower(parts[1]+parts[2]) != parts[1]+parts[2] {
+		return
+	}
+
+	if _, err := hex.Decode(traceID[:], []byte(parts[1])); err != nil || traceID == [16]byte{} {
+		return
+	}
+
+	if _, err := hex.Decode(parentID[:], []byte(parts[2])); err != nil || parentID == [8]byte{} {
+		return
+	}
+
+	return traceID, parentID, true
+}
+
+// newSpan creates a new, started span with a random ID and returns it
+func newSpan(name string, kind int) *Span {
+	s := &Span{
+		attributes: make(map[string]interface{}),
+		kind:       kind,
+		name:       name,
+		start:      time.Now(),
+	}
+
+	randomID(s.spanID[:])
+
+	return s
+}
+
+// randomID fills an ID with random bytes, ensuring it isn't all zeros
+func randomID(id []byte) {
+	for {
+		if _, err := rand.Read(id); err != nil {
+			// Fall back to a time-based ID
+			// NOTE: Reading random bytes should never fail
+			now := time.Now().UnixNano()
+			for n := range id {
+				id[n] = byte(now >> uint(8*(n%8)))
+			}
+		}
+
+		for _, b := range id {
+			if b != 0 {
+				return
+			}
+		}
+	}
+}
+
+/* End utility methods */
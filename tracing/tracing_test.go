@@ -0,0 +1,211 @@
+// Tests the tracing.go file
+package tracing
+
+import (
+	// Standard lib
+	"fmt"
+	"sync"
+
+	// Third-party
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type (
+	// Struct representing an exporter that stores spans in memory for tests
+	memoryExporter struct {
+		mutex sync.Mutex
+		spans []*Span
+	}
+)
+
+// Export stores a batch of spans
+func (e *memoryExporter) Export(spans []*Span) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.spans = append(e.spans, spans...)
+
+	return nil
+}
+
+var _ = Describe("tracing.go", func() {
+	var (
+		// Mock exporter to use within tests
+		e *memoryExporter
+	)
+
+	BeforeEach(func() {
+		// Enable tracing
+		e = &memoryExporter{}
+		SetExporter(e)
+	})
+
+	AfterEach(func() {
+		// Disable tracing
+		SetExporter(nil)
+	})
+
+	Describe("`StartRootSpan` method", func() {
+		Context("When tracing is disabled", func() {
+			It("Returns nil", func() {
+				// Disable tracing
+				SetExporter(nil)
+
+				// Verify return value
+				Expect(StartRootSpan("request", "")).To(BeNil())
+			})
+		})
+
+		Context("Without a traceparent", func() {
+			It("Starts a new trace", func() {
+				// Call method
+				s := StartRootSpan("request", "")
+
+				// Verify return value
+				Expect(s.kind).To(Equal(SPAN_KIND_SERVER))
+				Expect(s.traceID).To(Not(Equal([16]byte{})))
+				Expect(s.spanID).To(Not(Equal([8]byte{})))
+				Expect(s.parentID).To(Equal([8]byte{}))
+			})
+		})
+
+		Context("With a valid traceparent", func() {
+			It("Continues the caller's trace", func() {
+				// Call method
+				s := StartRootSpan("request", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+				// Verify return value
+				Expect(s.TraceID()).To(Equal("4bf92f3577b34da6a3ce929d0e0e4736"))
+				Expect(fmt.Sprintf("%x", s.parentID)).To(Equal("00f067aa0ba902b7"))
+			})
+		})
+	})
+
+	Describe("`StartSpan` method", func() {
+		Context("With a nil parent", func() {
+			It("Returns nil", func() {
+				// Verify return value
+				Expect(StartSpan(nil, "download", SPAN_KIND_CLIENT)).To(BeNil())
+			})
+		})
+
+		Context("With a parent", func() {
+			It("Starts a child span in the parent's trace", func() {
+				// Call method
+				parent := StartRootSpan("request", "")
+				s := StartSpan(parent, "download", SPAN_KIND_CLIENT)
+
+				// Verify return value
+				Expect(s.kind).To(Equal(SPAN_KIND_CLIENT))
+				Expect(s.traceID).To(Equal(parent.traceID))
+				Expect(s.parentID).To(Equal(parent.spanID))
+				Expect(s.spanID).To(Not(Equal(parent.spanID)))
+			})
+		})
+	})
+
+	Describe("Span methods", func() {
+		Context("With a nil span", func() {
+			It("Does nothing", func() {
+				var s *Span
+
+				// Call methods
+				s.SetAttribute("key", "value")
+				s.SetError(fmt.Errorf("Error"))
+				s.End()
+
+				// Verify return values
+				Expect(s.TraceID()).To(Equal(""))
+				Expect(s.Traceparent()).To(Equal(""))
+			})
+		})
+
+		Describe("`End` method", func() {
+			It("Queues the span for exporting once", func() {
+				// Call methods
+				s := StartRootSpan("request", "")
+				s.End()
+				s.End()
+				Flush()
+
+				// Verify spans were exported
+				Expect(e.spans).To(Equal([]*Span{s}))
+				Expect(s.end.IsZero()).To(BeFalse())
+			})
+		})
+
+		Describe("`SetAttribute` and `SetError` methods", func() {
+			It("Sets the span's attributes and error", func() {
+				// Call methods
+				s := StartRootSpan("request", "")
+				s.SetAttribute("status", 200)
+				s.SetError(nil)
+
+				// Verify values
+				Expect(s.attributes).To(HaveKeyWithValue("status", 200))
+				Expect(s.err).To(BeNil())
+
+				// Set error
+				s.SetError(fmt.Errorf("Error"))
+
+				// Verify values
+				Expect(s.err).To(MatchError("Error"))
+			})
+		})
+
+		Describe("`Traceparent` method", func() {
+			It("Returns a traceparent that can be parsed", func() {
+				// Call method
+				s := StartRootSpan("request", "")
+				traceparent := s.Traceparent()
+
+				// Verify return value
+				Expect(traceparent).To(MatchRegexp("^00-[0-9a-f]{32}-[0-9a-f]{16}-01$"))
+
+				traceID, parentID, ok := ParseTraceparent(traceparent)
+				Expect(ok).To(BeTrue())
+				Expect(traceID).To(Equal(s.traceID))
+				Expect(parentID).To(Equal(s.spanID))
+			})
+		})
+	})
+
+	Describe("`ParseTraceparent` method", func() {
+		It("Returns a boolean indicating if the traceparent is valid", func() {
+			// Verify return values
+			for traceparent, expected := range map[string]bool{
+				"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01":     true,
+				"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-abc": true,
+				"": false,
+				"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-abc": false,
+				"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01":     false,
+				"00-00000000000000000000000000000000-00f067aa0ba902b7-01":     false,
+				"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01":     false,
+				"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01":     false,
+				"00-4bf92f3577b34da6a3ce929d0e0e473-00f067aa0ba902b7-01":      false,
+				"00-4bf92f3577b34da6a3ce929d0e0e473z-00f067aa0ba902b7-01":     false,
+			} {
+				_, _, ok := ParseTraceparent(traceparent)
+				Expect(ok).To(Equal(expected), traceparent)
+			}
+		})
+	})
+
+	Describe("`Flush` method", func() {
+		It("Exports finished spans once", func() {
+			// Finish spans
+			parent := StartRootSpan("request", "")
+			child := StartSpan(parent, "decode", SPAN_KIND_INTERNAL)
+			child.End()
+			parent.End()
+
+			// Call method
+			Flush()
+			Flush()
+
+			// Verify spans were exported
+			Expect(e.spans).To(Equal([]*Span{child, parent}))
+		})
+	})
+})